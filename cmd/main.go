@@ -4,37 +4,121 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/mrasoolmirzaei/delivery-route-system/pkg/httpclient"
 	"github.com/mrasoolmirzaei/delivery-route-system/pkg/osrmclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/tracing"
+	"github.com/mrasoolmirzaei/delivery-route-system/registry"
 	"github.com/mrasoolmirzaei/delivery-route-system/server"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 )
 
+// routeFinder is the interface shared by the OSRM client and the service decorators wrapping it
+type routeFinder interface {
+	FindFastestRoutes(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error)
+	FindMatrix(ctx context.Context, profile service.Profile, sources, destinations []service.Location) (*service.Matrix, error)
+	FindRoute(ctx context.Context, profile service.Profile, source, destination service.Location, geometry service.GeometryFormat, steps bool) (*service.RouteDetails, error)
+	FindTrip(ctx context.Context, profile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (*service.Trip, error)
+}
+
 const (
 	serverPort         = ":8000"
 	defaultOSRMBaseURL = "http://router.project-osrm.org"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "pickup-points" {
+		os.Exit(runPickupPoints(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	logger := initLogger()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), &tracing.Config{
+		Exporter:    tracing.Exporter(envOrDefault("TRACING_EXPORTER", "", parseString)),
+		SampleRatio: envOrDefault("TRACING_SAMPLE_RATIO", 0.0, parseFloat),
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("failed to set up tracing")
+		return
+	}
+
 	osrmBaseURL := envOrDefault("OSRM_BASE_URL", defaultOSRMBaseURL, parseString)
 
-	routeService := service.NewRouteService(osrmclient.NewOSRMClient(&osrmclient.Config{
-		BaseURL: osrmBaseURL,
+	osrmClient, err := osrmclient.NewOSRMClient(&osrmclient.Config{
+		BaseURL:          osrmBaseURL,
+		BaseURLs:         envOrDefault("OSRM_BASE_URLS", []string(nil), parseList),
+		Policy:           osrmclient.SelectionPolicy(envOrDefault("OSRM_SELECTION_POLICY", "", parseString)),
+		FailoverAttempts: envOrDefault("OSRM_FAILOVER_ATTEMPTS", uint(0), parseUint),
 		HTTP: &httpclient.Config{
 			Log: logger.WithField("context", "osrmclient"),
+			CircuitBreaker: &httpclient.CircuitBreakerConfig{
+				FailureThreshold: envOrDefault("OSRM_CIRCUIT_FAILURE_THRESHOLD", uint(0), parseUint),
+				CoolDown:         envOrDefault("OSRM_CIRCUIT_COOL_DOWN", time.Duration(0), time.ParseDuration),
+			},
 		},
-	}))
+		Profiles:            profilesFromEnv(),
+		MaxTableCoordinates: envOrDefault("OSRM_MAX_TABLE_COORDINATES", 0, strconv.Atoi),
+		MaxURLLength:        envOrDefault("OSRM_MAX_URL_LENGTH", 0, strconv.Atoi),
+		MaxConcurrentChunks: envOrDefault("OSRM_MAX_CONCURRENT_CHUNKS", 0, strconv.Atoi),
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("failed to create OSRM client")
+		return
+	}
+
+	var finder routeFinder = osrmClient
+	if envOrDefault("ROUTE_BATCHING_ENABLED", false, strconv.ParseBool) {
+		finder = service.NewBatchingRouteFinder(finder, &service.BatchConfig{
+			Window:          envOrDefault("ROUTE_BATCH_WINDOW", time.Duration(0), time.ParseDuration),
+			MaxDestinations: envOrDefault("ROUTE_BATCH_MAX_DESTINATIONS", 0, strconv.Atoi),
+		})
+	}
+	if envOrDefault("ROUTE_CACHE_ENABLED", true, strconv.ParseBool) {
+		cache := service.NewCachingRouteFinder(finder, &service.CacheConfig{
+			TTL:       envOrDefault("ROUTE_CACHE_TTL", time.Duration(0), time.ParseDuration),
+			Precision: envOrDefault("ROUTE_CACHE_PRECISION", 0, strconv.Atoi),
+			MaxBytes:  envOrDefault("ROUTE_CACHE_MAX_BYTES", int64(0), parseInt64),
+		})
+		go logCacheStats(logger.WithField("context", "cache"), cache, envOrDefault("ROUTE_CACHE_STATS_INTERVAL", time.Minute, time.ParseDuration))
+		finder = cache
+	}
+	if envOrDefault("ROUTE_COALESCING_ENABLED", true, strconv.ParseBool) {
+		finder = service.NewCoalescingRouteFinder(finder)
+	}
+	if envOrDefault("ROUTE_FALLBACK_ENABLED", false, strconv.ParseBool) {
+		finder = service.NewFallbackRouteFinder(finder, &service.FallbackConfig{
+			DetourFactor: envOrDefault("ROUTE_FALLBACK_DETOUR_FACTOR", 0.0, parseFloat),
+			Speeds:       fallbackSpeedsFromEnv(),
+		})
+	}
+
+	routeService := service.NewRouteService(finder)
+
+	var pickupPoints registry.Store = registry.NewMemoryStore()
+	if path := envOrDefault("PICKUP_POINTS_FILE", "", parseString); path != "" {
+		fileStore, err := registry.NewFileStore(&registry.FileStoreConfig{Path: path})
+		if err != nil {
+			logger.WithError(err).Fatal("failed to open pickup point registry")
+			return
+		}
+		defer fileStore.Close()
+		pickupPoints = fileStore
+	}
+
 	logger.Info("Creating server...")
 	srv, err := server.NewServer(server.Config{
 		Logger:       logger.WithField("context", "server"),
 		RouteService: routeService,
+		OSRM:         osrmClient,
+		PickupPoints: pickupPoints,
+
+		NearestCandidateMultiplier: envOrDefault("NEAREST_CANDIDATE_MULTIPLIER", 0, strconv.Atoi),
 	})
 	if err != nil {
 		logger.WithError(err).Fatal("failed to create server")
@@ -62,11 +146,47 @@ func main() {
 	})
 
 	err = g.Wait()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		logger.WithError(flushErr).Error("failed to flush traces")
+	}
+
 	if err != nil {
 		logger.WithError(err).Fatal("server error")
 	}
 }
 
+// profilesFromEnv reads the OSRM instances serving the non-driving profiles from
+// OSRM_<PROFILE>_BASE_URLS and OSRM_<PROFILE>_PROFILE_NAME, profiles without base URLs are left out
+func profilesFromEnv() map[service.Profile]osrmclient.ProfileConfig {
+	profiles := make(map[service.Profile]osrmclient.ProfileConfig)
+	for _, p := range []service.Profile{service.ProfileCycling, service.ProfileWalking} {
+		prefix := "OSRM_" + strings.ToUpper(string(p))
+		baseURLs := envOrDefault(prefix+"_BASE_URLS", []string(nil), parseList)
+		if len(baseURLs) == 0 {
+			continue
+		}
+		profiles[p] = osrmclient.ProfileConfig{
+			Name:     envOrDefault(prefix+"_PROFILE_NAME", "", parseString),
+			BaseURLs: baseURLs,
+		}
+	}
+	return profiles
+}
+
+// fallbackSpeedsFromEnv reads the average speed in meters per second of every profile from ROUTE_FALLBACK_SPEED_<PROFILE>
+func fallbackSpeedsFromEnv() map[service.Profile]float64 {
+	speeds := make(map[service.Profile]float64)
+	for _, p := range []service.Profile{service.ProfileDriving, service.ProfileCycling, service.ProfileWalking} {
+		if speed := envOrDefault("ROUTE_FALLBACK_SPEED_"+strings.ToUpper(string(p)), 0.0, parseFloat); speed > 0 {
+			speeds[p] = speed
+		}
+	}
+	return speeds
+}
+
 func initLogger() *logrus.Entry {
 	log := logrus.New()
 	log.Out = os.Stdout
@@ -82,6 +202,24 @@ func initLogger() *logrus.Entry {
 	return log.WithField("context", "main")
 }
 
+// logCacheStats logs the route cache counters every interval, so that its TTL, precision and size can be tuned.
+// An interval of 0 disables it.
+func logCacheStats(log logrus.FieldLogger, cache *service.CachingRouteFinder, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for range time.Tick(interval) {
+		stats := cache.Stats()
+		log.WithFields(logrus.Fields{
+			"hits":      stats.Hits,
+			"misses":    stats.Misses,
+			"evictions": stats.Evictions,
+			"entries":   stats.Entries,
+			"bytes":     stats.Bytes,
+		}).Info("Route cache stats")
+	}
+}
+
 func envOrDefault[T any](env string, def T, parser func(string) (T, error)) T {
 	e, ok := os.LookupEnv(env)
 	if !ok {
@@ -96,3 +234,27 @@ func envOrDefault[T any](env string, def T, parser func(string) (T, error)) T {
 func parseString(s string) (string, error) {
 	return s, nil
 }
+
+// parseList parses a comma separated list, ignoring empty items
+func parseList(s string) ([]string, error) {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseUint(s string) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 0)
+	return uint(v), err
+}
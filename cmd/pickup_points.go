@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/registry"
+)
+
+const pickupPointsUsage = `usage:
+  pickup-points import [-registry file] [-format csv|geojson] [-dry-run] [file]
+  pickup-points export [-registry file] [-format csv|geojson]
+
+import reads the file, or stdin if omitted, and creates or updates the pickup points by external ID.
+export writes all pickup points to stdout. The registry defaults to PICKUP_POINTS_FILE.
+`
+
+// runPickupPoints runs the pickup-points subcommand on the registry file, without starting the server.
+// It returns the exit code.
+func runPickupPoints(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "export") {
+		fmt.Fprint(stderr, pickupPointsUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("pickup-points "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("registry", envOrDefault("PICKUP_POINTS_FILE", "", parseString), "pickup point registry file")
+	format := flags.String("format", "", "csv or geojson, defaults to the extension of the imported file or csv")
+	dryRun := flags.Bool("dry-run", false, "validate the file and report the changes without writing them")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if *path == "" {
+		fmt.Fprintln(stderr, "the registry file must be set with -registry or PICKUP_POINTS_FILE")
+		return 2
+	}
+	store, err := registry.NewFileStore(&registry.FileStoreConfig{Path: *path})
+	if errors.Is(err, registry.ErrLocked) {
+		fmt.Fprintf(stderr, "%v, stop the server or use the /pickup-points/import and /pickup-points/export endpoints instead\n", err)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if args[0] == "export" {
+		points, err := store.List(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if err := registry.Export(stdout, points, formatOrDefault(*format, "")); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	in, name := stdin, ""
+	if flags.NArg() > 0 {
+		name = flags.Arg(0)
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer file.Close()
+		in = file
+	}
+
+	result, err := registry.Import(ctx, store, in, formatOrDefault(*format, name), &registry.ImportOptions{DryRun: *dryRun})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for _, rowErr := range result.Errors {
+		fmt.Fprintf(stderr, "row %d %s: %s\n", rowErr.Row, rowErr.ExternalID, rowErr.Message)
+	}
+
+	switch {
+	case len(result.Errors) > 0:
+		fmt.Fprintf(stderr, "%d invalid rows, nothing imported\n", len(result.Errors))
+		return 1
+	case *dryRun:
+		fmt.Fprintf(stdout, "dry run: %d to create, %d to update\n", result.Created, result.Updated)
+	default:
+		fmt.Fprintf(stdout, "%d created, %d updated\n", result.Created, result.Updated)
+	}
+	return 0
+}
+
+// formatOrDefault returns the format, or the one matching the extension of the file, CSV if there is none
+func formatOrDefault(format, file string) registry.Format {
+	if format != "" {
+		return registry.Format(format)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".geojson", ".json":
+		return registry.FormatGeoJSON
+	default:
+		return registry.FormatCSV
+	}
+}
@@ -0,0 +1,184 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold    = 5
+	defaultCoolDown            = 10 * time.Second
+	defaultHalfOpenMaxRequests = 1
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned without calling the server while its circuit breaker is open
+type CircuitOpenError struct {
+	Host      string
+	OpenUntil time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for %s until %s", ErrCircuitOpen, e.Host, e.OpenUntil.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// RetryAfter is how long until the breaker lets a trial request through again
+func (e *CircuitOpenError) RetryAfter() time.Duration {
+	return max(time.Until(e.OpenUntil), 0)
+}
+
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed attempts that opens the circuit
+	FailureThreshold uint
+	// CoolDown is how long the circuit stays open before trial requests are let through (half-open)
+	CoolDown time.Duration
+	// HalfOpenMaxRequests is the number of concurrent trial requests allowed while half-open
+	HalfOpenMaxRequests uint
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker tracks consecutive failures of one host.
+// closed: requests pass, failures are counted; open: requests fail fast until the cool-down is over;
+// half-open: a limited number of trial requests pass, one success closes the circuit and one failure opens it again.
+type circuitBreaker struct {
+	host             string
+	threshold        uint
+	coolDown         time.Duration
+	halfOpenMax      uint
+	now              func() time.Time
+	onStateChange    func(host string, from, to circuitState)
+	mu               sync.Mutex
+	state            circuitState
+	failures         uint
+	openedAt         time.Time
+	halfOpenInFlight uint
+}
+
+func newCircuitBreaker(host string, cfg *CircuitBreakerConfig, onStateChange func(host string, from, to circuitState)) *circuitBreaker {
+	b := &circuitBreaker{
+		host:          host,
+		threshold:     defaultFailureThreshold,
+		coolDown:      defaultCoolDown,
+		halfOpenMax:   defaultHalfOpenMaxRequests,
+		now:           time.Now,
+		onStateChange: onStateChange,
+	}
+
+	if cfg != nil {
+		if cfg.FailureThreshold > 0 {
+			b.threshold = cfg.FailureThreshold
+		}
+		if cfg.CoolDown > 0 {
+			b.coolDown = cfg.CoolDown
+		}
+		if cfg.HalfOpenMaxRequests > 0 {
+			b.halfOpenMax = cfg.HalfOpenMaxRequests
+		}
+	}
+
+	return b
+}
+
+// allow returns a *CircuitOpenError if the request must not be sent
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitOpen {
+		if b.now().Before(b.openedAt.Add(b.coolDown)) {
+			return &CircuitOpenError{Host: b.host, OpenUntil: b.openedAt.Add(b.coolDown)}
+		}
+		b.setState(circuitHalfOpen)
+		b.halfOpenInFlight = 0
+	}
+
+	if b.state == circuitHalfOpen {
+		if b.halfOpenInFlight >= b.halfOpenMax {
+			return &CircuitOpenError{Host: b.host, OpenUntil: b.now()}
+		}
+		b.halfOpenInFlight++
+	}
+
+	return nil
+}
+
+func (b *circuitBreaker) onSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == circuitHalfOpen {
+		b.setState(circuitClosed)
+	}
+}
+
+// onAbandoned gives back a half-open trial slot when the request was cancelled before the server answered
+func (b *circuitBreaker) onAbandoned() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+}
+
+func (b *circuitBreaker) onFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitHalfOpen:
+		b.open()
+	case circuitClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+// open must be called with b.mu held
+func (b *circuitBreaker) open() {
+	b.setState(circuitOpen)
+	b.openedAt = b.now()
+	b.failures = 0
+}
+
+// setState must be called with b.mu held
+func (b *circuitBreaker) setState(state circuitState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(b.host, from, state)
+	}
+}
@@ -4,14 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/mrasoolmirzaei/delivery-route-system/pkg/httpclient")
+
 const (
 	defaultTimeout         = 3 * time.Second
 	defaultMaxRetries      = 10
@@ -19,19 +28,37 @@ const (
 	defaultMaxIdleConns    = 100
 	defaultMaxConnsPerHost = 10
 	defaultIdleConnTimeout = 90 * time.Second
+	maxErrorBodyBytes      = 64 << 10
 )
 
+// StatusError is returned when the server answers with a non-200 status code.
+// Body holds the (truncated) response body so callers can decode API specific error payloads.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Status)
+}
+
 type HTTPClient struct {
 	client     *http.Client
 	log        logrus.FieldLogger
 	maxRetries uint
 	retryDelay time.Duration
+	breakerCfg *CircuitBreakerConfig
+	breakersMu sync.Mutex
+	breakers   map[string]*circuitBreaker
 }
 
 type Config struct {
 	Log         logrus.FieldLogger
 	RetryConfig *RetryConfig
 	Timeout     time.Duration
+	// CircuitBreaker configures the per host circuit breaker, defaults are used when nil
+	CircuitBreaker *CircuitBreakerConfig
 }
 
 type RetryConfig struct {
@@ -61,6 +88,8 @@ func NewHTTPClient(cfg *Config) *HTTPClient {
 		log:        cfg.Log,
 		maxRetries: defaultMaxRetries,
 		retryDelay: defaultRetryDelay,
+		breakerCfg: cfg.CircuitBreaker,
+		breakers:   make(map[string]*circuitBreaker),
 	}
 
 	if cfg.RetryConfig != nil {
@@ -76,37 +105,108 @@ func (c *HTTPClient) shouldRetryOnStatus(statusCode int) bool {
 	return statusCode >= http.StatusInternalServerError
 }
 
+// breaker returns the circuit breaker of the host, creating it on first use
+func (c *HTTPClient) breaker(host string) *circuitBreaker {
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = newCircuitBreaker(host, c.breakerCfg, func(host string, from, to circuitState) {
+			c.log.Warnf("circuit breaker for %s changed from %s to %s", host, from, to)
+			circuitBreakerState.WithLabelValues(host).Set(float64(to))
+		})
+		c.breakers[host] = b
+		circuitBreakerState.WithLabelValues(host).Set(float64(circuitClosed))
+	}
+	return b
+}
+
+// do sends one attempt of the request in its own client span, propagating the trace context to the server
+func (c *HTTPClient) do(req *http.Request, breaker *circuitBreaker, attempt int) (resp *http.Response, err error) {
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.HTTPRequestResendCount(attempt),
+		),
+	)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if allowErr := breaker.allow(); allowErr != nil {
+		return nil, retry.Unrecoverable(allowErr)
+	}
+
+	attemptReq := req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(attemptReq.Header))
+
+	resp, err = c.client.Do(attemptReq)
+	if err != nil {
+		// The caller giving up says nothing about the health of the server
+		if req.Context().Err() != nil {
+			breaker.onAbandoned()
+		} else {
+			breaker.onFailure()
+		}
+		return nil, err
+	}
+	if resp == nil {
+		breaker.onFailure()
+		return nil, fmt.Errorf("response is nil")
+	}
+
+	if c.shouldRetryOnStatus(resp.StatusCode) {
+		breaker.onFailure()
+		resp.Body.Close()
+		return resp, fmt.Errorf("retryable status code: %d", resp.StatusCode)
+	}
+	breaker.onSuccess()
+	return resp, nil
+}
+
 func (c *HTTPClient) Get(ctx context.Context, url string, response any) error {
+	return c.GetWithAttempts(ctx, url, response, c.maxRetries)
+}
+
+// GetWithAttempts is Get with at most the given number of attempts instead of the configured retries,
+// e.g. to give up on a server sooner when another one can be tried. It makes at least one attempt.
+func (c *HTTPClient) GetWithAttempts(ctx context.Context, url string, response any, attempts uint) error {
+	attempts = max(attempts, 1)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		c.log.WithError(err).Errorf("failed to create request to get %s", url)
 		return err
 	}
 
+	breaker := c.breaker(req.URL.Host)
+
 	var resp *http.Response
+	var attempt int
 	err = retry.Do(
 		func() error {
 			var doErr error
-			resp, doErr = c.client.Do(req)
-			if doErr != nil {
-				return doErr
-			}
-			if resp == nil {
-				return fmt.Errorf("response is nil")
-			}
-
-			if c.shouldRetryOnStatus(resp.StatusCode) {
-				resp.Body.Close()
-				return fmt.Errorf("retryable status code: %d", resp.StatusCode)
-			}
-			return nil
+			resp, doErr = c.do(req, breaker, attempt)
+			attempt++
+			return doErr
 		},
-		retry.Attempts(c.maxRetries),
+		retry.Attempts(attempts),
 		retry.Delay(c.retryDelay),
 		retry.DelayType(retry.BackOffDelay),
 		retry.LastErrorOnly(true),
 		retry.Context(ctx),
 		retry.OnRetry(func(n uint, retryErr error) {
+			retriesTotal.WithLabelValues(req.URL.Host).Inc()
 			if resp != nil {
 				c.log.WithError(retryErr).Warnf("retry attempt %d for %s (status: %d)", n+1, url, resp.StatusCode)
 				resp.Body.Close()
@@ -128,7 +228,12 @@ func (c *HTTPClient) Get(ctx context.Context, url string, response any) error {
 
 	if resp.StatusCode != http.StatusOK {
 		c.log.Errorf("failed to get %s : %s", url, resp.Status)
-		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, resp.Status)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       body,
+		}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestGet_Success(t *testing.T) {
@@ -89,6 +93,29 @@ func TestGet_MaxRetriesExceeded(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestGetWithAttempts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&Config{
+		Log: logrus.New(),
+		RetryConfig: &RetryConfig{
+			MaxRetries: 5,
+			BaseDelay:  10 * time.Millisecond,
+		},
+	})
+
+	var response map[string]interface{}
+	err := client.GetWithAttempts(context.Background(), server.URL, &response, 1)
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
 func TestGet_InvalidJSON(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -111,3 +138,149 @@ func TestGet_InvalidJSON(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid character")
 }
+
+func TestGet_ClientErrorReturnsStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"TooBig"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&Config{
+		Log: logrus.New(),
+		RetryConfig: &RetryConfig{
+			MaxRetries: 3,
+			BaseDelay:  10 * time.Millisecond,
+		},
+	})
+
+	var response map[string]interface{}
+	err := client.Get(context.Background(), server.URL, &response)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.JSONEq(t, `{"code":"TooBig"}`, string(statusErr.Body))
+}
+
+func TestGet_CircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var requestCount atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&Config{
+		Log: logrus.New(),
+		RetryConfig: &RetryConfig{
+			MaxRetries: 2,
+			BaseDelay:  time.Millisecond,
+		},
+		CircuitBreaker: &CircuitBreakerConfig{
+			FailureThreshold: 3,
+			CoolDown:         50 * time.Millisecond,
+		},
+	})
+
+	var response map[string]interface{}
+
+	// The third failed attempt opens the circuit and stops the retries of the second call
+	require.Error(t, client.Get(context.Background(), server.URL, &response))
+	err := client.Get(context.Background(), server.URL, &response)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), requestCount.Load())
+
+	// While open, requests fail fast without reaching the server
+	err = client.Get(context.Background(), server.URL, &response)
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Greater(t, openErr.RetryAfter(), time.Duration(0))
+	assert.Equal(t, int32(3), requestCount.Load())
+
+	// After the cool-down a successful trial request closes the circuit
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, client.Get(context.Background(), server.URL, &response))
+	require.NoError(t, client.Get(context.Background(), server.URL, &response))
+	assert.Equal(t, int32(5), requestCount.Load())
+}
+
+func TestGet_CircuitBreakerReopensOnFailedTrial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&Config{
+		Log: logrus.New(),
+		RetryConfig: &RetryConfig{
+			MaxRetries: 1,
+			BaseDelay:  time.Millisecond,
+		},
+		CircuitBreaker: &CircuitBreakerConfig{
+			FailureThreshold: 1,
+			CoolDown:         20 * time.Millisecond,
+		},
+	})
+
+	var response map[string]interface{}
+	require.Error(t, client.Get(context.Background(), server.URL, &response))
+	require.ErrorIs(t, client.Get(context.Background(), server.URL, &response), ErrCircuitOpen)
+
+	time.Sleep(30 * time.Millisecond)
+	err := client.Get(context.Background(), server.URL, &response)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, client.Get(context.Background(), server.URL, &response), ErrCircuitOpen)
+}
+
+func TestGet_PropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if len(traceparents) < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&Config{
+		Log: logrus.New(),
+		RetryConfig: &RetryConfig{
+			MaxRetries: 2,
+			BaseDelay:  time.Millisecond,
+		},
+	})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	var response map[string]interface{}
+	require.NoError(t, client.Get(ctx, server.URL, &response))
+
+	require.Len(t, traceparents, 2)
+	for _, tp := range traceparents {
+		assert.Contains(t, tp, "4bf92f3577b34da6a3ce929d0e0e4736")
+	}
+}
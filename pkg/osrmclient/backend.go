@@ -0,0 +1,188 @@
+package osrmclient
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SelectionPolicy decides in which order the OSRM backends are tried
+type SelectionPolicy string
+
+const (
+	// PolicyRoundRobin spreads requests evenly over the healthy backends
+	PolicyRoundRobin SelectionPolicy = "round-robin"
+	// PolicyLeastLatency prefers the healthy backend with the lowest average latency
+	PolicyLeastLatency SelectionPolicy = "least-latency"
+	// PolicyPrimarySecondary prefers backends in the configured order, later ones are only used on failover
+	PolicyPrimarySecondary SelectionPolicy = "primary-secondary"
+)
+
+const (
+	defaultBackendFailureThreshold = 3
+	defaultBackendEjectDuration    = 10 * time.Second
+	// latencyWeight is the weight of the latest sample in the moving average latency
+	latencyWeight = 0.2
+)
+
+func (p SelectionPolicy) Validate() error {
+	switch p {
+	case PolicyRoundRobin, PolicyLeastLatency, PolicyPrimarySecondary:
+		return nil
+	default:
+		return fmt.Errorf("unknown OSRM backend selection policy %q", p)
+	}
+}
+
+// BackendStats is a snapshot of the passive health tracking of one backend
+type BackendStats struct {
+	BaseURL  string
+	Healthy  bool
+	Latency  time.Duration
+	Requests uint64
+	Failures uint64
+}
+
+type backend struct {
+	baseURL string
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	unhealthyUntil      time.Time
+	latency             time.Duration
+	requests            uint64
+	failures            uint64
+}
+
+// backendPool tracks the health of every backend from the outcome of real requests
+// and orders them according to the selection policy
+type backendPool struct {
+	backends         []*backend
+	policy           SelectionPolicy
+	failureThreshold int
+	ejectDuration    time.Duration
+	now              func() time.Time
+	next             atomic.Uint64
+}
+
+func newBackendPool(baseURLs []string, policy SelectionPolicy) *backendPool {
+	backends := make([]*backend, len(baseURLs))
+	for i, u := range baseURLs {
+		backends[i] = &backend{baseURL: u}
+		backendHealthy.WithLabelValues(u).Set(1)
+	}
+
+	return &backendPool{
+		backends:         backends,
+		policy:           policy,
+		failureThreshold: defaultBackendFailureThreshold,
+		ejectDuration:    defaultBackendEjectDuration,
+		now:              time.Now,
+	}
+}
+
+// candidates returns every backend in the order they should be tried.
+// Unhealthy backends come last so they are still used when nothing else is left.
+func (p *backendPool) candidates() []*backend {
+	ordered := make([]*backend, len(p.backends))
+	copy(ordered, p.backends)
+
+	switch p.policy {
+	case PolicyRoundRobin:
+		start := int(p.next.Add(1)-1) % len(ordered)
+		ordered = append(slices.Clone(ordered[start:]), ordered[:start]...)
+	case PolicyLeastLatency:
+		latencies := make(map[*backend]time.Duration, len(ordered))
+		for _, b := range ordered {
+			latencies[b] = b.stats(p.now()).Latency
+		}
+		// Backends without samples yet sort first, so they get measured
+		slices.SortStableFunc(ordered, func(a, b *backend) int {
+			return cmp.Compare(latencies[a], latencies[b])
+		})
+	}
+
+	now := p.now()
+	healthy := make([]*backend, 0, len(ordered))
+	unhealthy := make([]*backend, 0)
+	for _, b := range ordered {
+		if b.healthy(now) {
+			healthy = append(healthy, b)
+		} else {
+			unhealthy = append(unhealthy, b)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *backendPool) stats() []BackendStats {
+	now := p.now()
+	stats := make([]BackendStats, len(p.backends))
+	for i, b := range p.backends {
+		stats[i] = b.stats(now)
+	}
+	return stats
+}
+
+func (p *backendPool) maxBaseURLLength() int {
+	maxLength := 0
+	for _, b := range p.backends {
+		maxLength = max(maxLength, len(b.baseURL))
+	}
+	return maxLength
+}
+
+func (p *backendPool) onSuccess(b *backend, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backendRequestsTotal.WithLabelValues(b.baseURL, "success").Inc()
+	backendHealthy.WithLabelValues(b.baseURL).Set(1)
+	b.requests++
+	b.consecutiveFailures = 0
+	b.unhealthyUntil = time.Time{}
+	if b.latency == 0 {
+		b.latency = latency
+	} else {
+		b.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(b.latency))
+	}
+}
+
+// onFailure marks the backend unhealthy for a while once it failed too many times in a row
+func (p *backendPool) onFailure(b *backend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backendRequestsTotal.WithLabelValues(b.baseURL, "failure").Inc()
+	b.requests++
+	b.failures++
+	b.consecutiveFailures++
+	if b.consecutiveFailures >= p.failureThreshold {
+		b.unhealthyUntil = p.now().Add(p.ejectDuration)
+		backendHealthy.WithLabelValues(b.baseURL).Set(0)
+	}
+}
+
+func (b *backend) healthy(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !now.Before(b.unhealthyUntil)
+}
+
+func (b *backend) stats(now time.Time) BackendStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BackendStats{
+		BaseURL:  b.baseURL,
+		Healthy:  !now.Before(b.unhealthyUntil),
+		Latency:  b.latency,
+		Requests: b.requests,
+		Failures: b.failures,
+	}
+}
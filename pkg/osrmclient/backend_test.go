@@ -0,0 +1,140 @@
+package osrmclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/httpclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unavailableTable(coords []string) (int, any) {
+	return http.StatusBadGateway, nil
+}
+
+func TestNewOSRMClient_UnknownPolicy(t *testing.T) {
+	_, err := NewOSRMClient(&Config{Policy: "random"})
+
+	assert.Error(t, err)
+}
+
+func TestFindFastestRoutes_FailsOverOnServerError(t *testing.T) {
+	down, downRequests := newTableServer(t, unavailableTable)
+	up, upRequests := newTableServer(t, latitudeTable)
+	client := newTestClient(t, "", Config{
+		BaseURLs: []string{down.URL, up.URL},
+		Policy:   PolicyPrimarySecondary,
+	})
+
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+	require.NoError(t, err)
+	assert.Len(t, routes, 2)
+	assert.Equal(t, int32(1), downRequests.Load())
+	assert.Equal(t, int32(1), upRequests.Load())
+
+	stats := client.BackendStats()
+	assert.Equal(t, uint64(1), stats[0].Failures)
+	assert.Equal(t, uint64(0), stats[1].Failures)
+	assert.Greater(t, stats[1].Latency, time.Duration(0))
+}
+
+func TestFindFastestRoutes_FailsOverFromHangingBackend(t *testing.T) {
+	var hangingRequests atomic.Int32
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hangingRequests.Add(1)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hanging.Close)
+	up, upRequests := newTableServer(t, latitudeTable)
+	client, err := NewOSRMClient(&Config{
+		BaseURLs: []string{hanging.URL, up.URL},
+		Policy:   PolicyPrimarySecondary,
+		HTTP: &httpclient.Config{
+			Log:         logrus.New(),
+			Timeout:     100 * time.Millisecond,
+			RetryConfig: &httpclient.RetryConfig{MaxRetries: 10, BaseDelay: 100 * time.Millisecond},
+		},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+	require.NoError(t, err)
+	assert.Len(t, routes, 2)
+	// the hanging backend costs one timeout, not the retries of the HTTP client
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), hangingRequests.Load())
+	assert.Equal(t, int32(1), upRequests.Load())
+}
+
+func TestFindFastestRoutes_DoesNotFailOverOnOSRMError(t *testing.T) {
+	first, _ := newTableServer(t, func(coords []string) (int, any) {
+		return http.StatusBadRequest, &TableResponse{Code: CodeNoSegment}
+	})
+	second, secondRequests := newTableServer(t, latitudeTable)
+	client := newTestClient(t, "", Config{
+		BaseURLs: []string{first.URL, second.URL},
+		Policy:   PolicyPrimarySecondary,
+	})
+
+	_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(1))
+
+	assert.ErrorIs(t, err, ErrNoSegment)
+	assert.Equal(t, int32(0), secondRequests.Load())
+}
+
+func TestFindFastestRoutes_EjectsUnhealthyBackend(t *testing.T) {
+	down, downRequests := newTableServer(t, unavailableTable)
+	up, upRequests := newTableServer(t, latitudeTable)
+	client := newTestClient(t, "", Config{
+		BaseURLs: []string{down.URL, up.URL},
+		Policy:   PolicyPrimarySecondary,
+	})
+
+	for i := 0; i < defaultBackendFailureThreshold+2; i++ {
+		_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(1))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(defaultBackendFailureThreshold), downRequests.Load())
+	assert.Equal(t, int32(defaultBackendFailureThreshold+2), upRequests.Load())
+	assert.False(t, client.BackendStats()[0].Healthy)
+}
+
+func TestBackendPool_RoundRobin(t *testing.T) {
+	pool := newBackendPool([]string{"a", "b", "c"}, PolicyRoundRobin)
+
+	first := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		first = append(first, pool.candidates()[0].baseURL)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "a"}, first)
+	assert.Len(t, pool.candidates(), 3)
+}
+
+func TestBackendPool_LeastLatency(t *testing.T) {
+	pool := newBackendPool([]string{"a", "b", "c"}, PolicyLeastLatency)
+	pool.onSuccess(pool.backends[0], 30*time.Millisecond)
+	pool.onSuccess(pool.backends[1], 10*time.Millisecond)
+	pool.onSuccess(pool.backends[2], 20*time.Millisecond)
+	for i := 0; i < defaultBackendFailureThreshold; i++ {
+		pool.onFailure(pool.backends[1])
+	}
+
+	order := make([]string, 0)
+	for _, b := range pool.candidates() {
+		order = append(order, b.baseURL)
+	}
+
+	assert.Equal(t, []string{"c", "a", "b"}, order)
+}
@@ -2,15 +2,25 @@ package osrmclient
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/mrasoolmirzaei/delivery-route-system/pkg/httpclient"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
+var tracer = otel.Tracer("github.com/mrasoolmirzaei/delivery-route-system/pkg/osrmclient")
+
 // OSRM Error codes as defined in the API documentation
 const (
 	CodeOk             = "Ok"
@@ -22,6 +32,8 @@ const (
 	CodeInvalidValue   = "InvalidValue"
 	CodeNoSegment      = "NoSegment"
 	CodeTooBig         = "TooBig"
+	CodeNoRoute        = "NoRoute"
+	CodeNoTrips        = "NoTrips"
 )
 
 var (
@@ -33,39 +45,177 @@ var (
 	ErrInvalidValue   = errors.New("OSRM: query parameters are invalid")
 	ErrNoSegment      = errors.New("OSRM: one of the supplied input coordinates could not snap to street segment")
 	ErrTooBig         = errors.New("OSRM: request size violates service specific request size restrictions")
-	ErrUnexpected     = errors.New("OSRM: unexpected table response structure")
+	ErrNoRoute        = errors.New("OSRM: no route found")
+	ErrNoTrips        = errors.New("OSRM: no trips found")
+	ErrUnexpected     = errors.New("OSRM: unexpected response structure")
 )
 
 type TableResponse struct {
-	Code      string      `json:"code"`
-	Message   string      `json:"message,omitempty"`
-	Durations [][]float64 `json:"durations"`
-	Distances [][]float64 `json:"distances"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+	// Durations and Distances are null for pairs that can't be routed
+	Durations    [][]*float64 `json:"durations"`
+	Distances    [][]*float64 `json:"distances"`
+	Sources      []Waypoint   `json:"sources,omitempty"`
+	Destinations []Waypoint   `json:"destinations,omitempty"`
+}
+
+// Waypoint is an input coordinate snapped to the road network
+type Waypoint struct {
+	Hint string `json:"hint,omitempty"`
+	// Distance is the distance in meters between the input coordinate and Location
+	Distance float64 `json:"distance"`
+	Name     string  `json:"name"`
+	// Location is the snapped coordinate as [longitude, latitude]
+	Location [2]float64 `json:"location"`
+}
+
+func (w Waypoint) location() service.Location {
+	return service.Coordinate{Lat: w.Location[1], Lon: w.Location[0]}.Location()
+}
+
+// osrmCoordinate formats the location in the longitude,latitude order OSRM expects
+func osrmCoordinate(l service.Location) (string, error) {
+	c, err := l.Coordinate()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidValue, err)
+	}
+	return c.Format(service.CoordOrderLonLat), nil
+}
+
+func osrmCoordinates(locations []service.Location) ([]string, error) {
+	coords := make([]string, len(locations))
+	for i, l := range locations {
+		c, err := osrmCoordinate(l)
+		if err != nil {
+			return nil, err
+		}
+		coords[i] = c
+	}
+	return coords, nil
+}
+
+// ChunkError wraps a failure of one table request when the destinations were split into chunks.
+// Start and End are the (0-based, end exclusive) indices of the destinations covered by the chunk.
+type ChunkError struct {
+	Chunk int
+	Start int
+	End   int
+	Err   error
 }
 
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (destinations %d-%d): %v", e.Chunk, e.Start, e.End-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// OSRM services, as they appear in request paths
+const (
+	serviceTable = "table"
+	serviceRoute = "route"
+	serviceTrip  = "trip"
+)
+
 const (
 	defaultOSRMBaseURL = "http://router.project-osrm.org"
+	// OSRM's default --max-table-size is 100 coordinates, source included
+	defaultMaxTableCoordinates = 100
+	defaultMaxURLLength        = 8000
+	defaultMaxConcurrentChunks = 4
+	defaultFailoverAttempts    = 1
 )
 
 type OSRMClient struct {
-	client  *httpclient.HTTPClient
-	log     logrus.FieldLogger
-	baseURL string
+	client              *httpclient.HTTPClient
+	log                 logrus.FieldLogger
+	profiles            map[service.Profile]*profile
+	maxTableCoordinates int
+	maxURLLength        int
+	maxConcurrentChunks int
+	failoverAttempts    uint
 }
 
 type Config struct {
+	// BaseURL is the OSRM instance serving the driving profile
 	BaseURL string
-	HTTP    *httpclient.Config
+	// BaseURLs lists several OSRM instances serving the same data, BaseURL is ignored when set
+	BaseURLs []string
+	// Profiles maps travel profiles to the OSRM instances serving them, since each instance usually serves one profile.
+	// The driving profile defaults to BaseURL/BaseURLs, other profiles are only supported when configured.
+	Profiles map[service.Profile]ProfileConfig
+	// Policy decides in which order the backends are tried, defaults to round-robin
+	Policy SelectionPolicy
+	// FailoverAttempts is the number of attempts made on a backend before failing over to the next one,
+	// defaults to 1. The retries of the HTTP client are only used when there is a single backend.
+	FailoverAttempts uint
+	HTTP             *httpclient.Config
+	// MaxTableCoordinates is the max number of coordinates (source included) sent in one table request
+	MaxTableCoordinates int
+	// MaxURLLength is the max length of one table request URL
+	MaxURLLength int
+	// MaxConcurrentChunks bounds how many chunked table requests run in parallel
+	MaxConcurrentChunks int
 }
 
-func NewOSRMClient(cfg *Config) *OSRMClient {
+type ProfileConfig struct {
+	// Name is the profile name used in request paths, defaults to the travel profile itself
+	Name string
+	// BaseURLs lists the OSRM instances serving the profile
+	BaseURLs []string
+}
+
+// profile holds the OSRM instances serving one travel profile
+type profile struct {
+	name     string
+	backends *backendPool
+}
+
+// chunk is a [start, end) range of destinations sent in one table request
+type chunk struct {
+	start int
+	end   int
+}
+
+func NewOSRMClient(cfg *Config) (*OSRMClient, error) {
 	if cfg == nil {
 		cfg = &Config{}
 	}
 
-	baseURL := defaultOSRMBaseURL
-	if cfg.BaseURL != "" {
-		baseURL = cfg.BaseURL
+	baseURLs := cfg.BaseURLs
+	if len(baseURLs) == 0 {
+		baseURL := defaultOSRMBaseURL
+		if cfg.BaseURL != "" {
+			baseURL = cfg.BaseURL
+		}
+		baseURLs = []string{baseURL}
+	}
+
+	policy := PolicyRoundRobin
+	if cfg.Policy != "" {
+		policy = cfg.Policy
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	profiles := map[service.Profile]*profile{
+		service.ProfileDriving: {name: string(service.ProfileDriving), backends: newBackendPool(baseURLs, policy)},
+	}
+	for p, profileCfg := range cfg.Profiles {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if len(profileCfg.BaseURLs) == 0 {
+			return nil, fmt.Errorf("no OSRM base URL configured for the %s profile", p)
+		}
+		name := string(p)
+		if profileCfg.Name != "" {
+			name = profileCfg.Name
+		}
+		profiles[p] = &profile{name: name, backends: newBackendPool(profileCfg.BaseURLs, policy)}
 	}
 
 	httpCfg := cfg.HTTP
@@ -73,33 +223,166 @@ func NewOSRMClient(cfg *Config) *OSRMClient {
 		httpCfg = &httpclient.Config{}
 	}
 
+	maxTableCoordinates := defaultMaxTableCoordinates
+	if cfg.MaxTableCoordinates > 1 {
+		maxTableCoordinates = cfg.MaxTableCoordinates
+	}
+
+	maxURLLength := defaultMaxURLLength
+	if cfg.MaxURLLength > 0 {
+		maxURLLength = cfg.MaxURLLength
+	}
+
+	maxConcurrentChunks := defaultMaxConcurrentChunks
+	if cfg.MaxConcurrentChunks > 0 {
+		maxConcurrentChunks = cfg.MaxConcurrentChunks
+	}
+
+	failoverAttempts := uint(defaultFailoverAttempts)
+	if cfg.FailoverAttempts > 0 {
+		failoverAttempts = cfg.FailoverAttempts
+	}
+
 	return &OSRMClient{
-		client:  httpclient.NewHTTPClient(httpCfg),
-		log:     httpCfg.Log,
-		baseURL: baseURL,
+		client:              httpclient.NewHTTPClient(httpCfg),
+		log:                 httpCfg.Log,
+		profiles:            profiles,
+		maxTableCoordinates: maxTableCoordinates,
+		maxURLLength:        maxURLLength,
+		maxConcurrentChunks: maxConcurrentChunks,
+		failoverAttempts:    failoverAttempts,
+	}, nil
+}
+
+// BackendStats returns the health, latency and request counters of every configured OSRM backend
+func (c *OSRMClient) BackendStats() []BackendStats {
+	stats := make([]BackendStats, 0)
+	for _, p := range []service.Profile{service.ProfileDriving, service.ProfileCycling, service.ProfileWalking} {
+		if prof, ok := c.profiles[p]; ok {
+			stats = append(stats, prof.backends.stats()...)
+		}
 	}
+	return stats
 }
 
-func (c *OSRMClient) FindFastestRoutes(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
-	routes := make([]*service.Route, 0, len(destinations))
-	sourceStr := source.String()
-	destinationsStr := make([]string, 0, len(destinations))
-	for _, d := range destinations {
-		destinationsStr = append(destinationsStr, d.String())
+// Ping checks that the driving backends answer with a one-destination table request,
+// it is meant for health checks and bypasses any caching in front of the client
+func (c *OSRMClient) Ping(ctx context.Context) error {
+	_, err := c.FindFastestRoutes(ctx, service.ProfileDriving, "13,14", []service.Location{"13.1234,12.7890"})
+	return err
+}
+
+// FindFastestRoutes returns one route per destination, in the order of destinations.
+// Destinations that don't fit in one table request are split into chunks which are queried concurrently.
+func (c *OSRMClient) FindFastestRoutes(ctx context.Context, travelProfile service.Profile, source service.Location, destinations []service.Location) (routes []*service.Route, err error) {
+	ctx, span := tracer.Start(ctx, "OSRMClient.FindFastestRoutes")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	p, ok := c.profiles[travelProfile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", service.ErrUnsupportedProfile, travelProfile)
 	}
-	url := c.findNearestRoutesURL(sourceStr, destinationsStr)
+
+	sourceCoord, err := osrmCoordinate(source)
+	if err != nil {
+		return nil, err
+	}
+	destinationCoords, err := osrmCoordinates(destinations)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := c.chunkDestinations(p, sourceCoord, destinationCoords)
+	span.SetAttributes(
+		attribute.String("osrm.profile", p.name),
+		attribute.Int("osrm.destinations", len(destinations)),
+		attribute.Int("osrm.chunks", len(chunks)),
+	)
+	if len(chunks) == 1 {
+		return c.findTableRoutes(ctx, p, sourceCoord, destinationCoords, destinations)
+	}
+
+	c.log.Debugf("splitting %d destinations into %d table requests", len(destinations), len(chunks))
+
+	routes = make([]*service.Route, len(destinations))
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(c.maxConcurrentChunks)
+	for i, ch := range chunks {
+		g.Go(func() error {
+			chunkRoutes, err := c.findTableRoutes(gCtx, p, sourceCoord, destinationCoords[ch.start:ch.end], destinations[ch.start:ch.end])
+			if err != nil {
+				return &ChunkError{Chunk: i, Start: ch.start, End: ch.end, Err: err}
+			}
+			copy(routes[ch.start:ch.end], chunkRoutes)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// chunkDestinations greedily packs destinations into chunks that respect both the coordinate and URL length limits
+func (c *OSRMClient) chunkDestinations(p *profile, source string, destinations []string) []chunk {
+	baseLength := p.backends.maxBaseURLLength() + len(c.findNearestRoutesPath(p.name, source, nil))
+	// One coordinate of every request is taken by the source
+	return packChunks(destinations, c.maxTableCoordinates-1, baseLength, c.maxURLLength, 0)
+}
+
+// packChunks greedily packs coordinates into chunks of at most maxCoords coordinates, whose URL stays within
+// maxLength given the length of the rest of the URL. extra is added to the length of every coordinate.
+func packChunks(coords []string, maxCoords, baseLength, maxLength, extra int) []chunk {
+	chunks := make([]chunk, 0, 1)
+	current := chunk{}
+	length := baseLength
+	for i, d := range coords {
+		// +1 for the ";" separating the coordinate from the previous one
+		dLength := len(d) + 1 + extra
+		full := current.end-current.start >= maxCoords || length+dLength > maxLength
+		if current.end > current.start && full {
+			chunks = append(chunks, current)
+			current = chunk{start: i, end: i}
+			length = baseLength
+		}
+		current.end = i + 1
+		length += dLength
+	}
+
+	return append(chunks, current)
+}
+
+// findTableRoutes sends one table request, source and destinationCoords are already in OSRM's longitude,latitude order
+func (c *OSRMClient) findTableRoutes(ctx context.Context, p *profile, source string, destinationCoords []string, destinations []service.Location) ([]*service.Route, error) {
+	routes := make([]*service.Route, 0, len(destinations))
+	path := c.findNearestRoutesPath(p.name, source, destinationCoords)
 
 	tableResponse := &TableResponse{}
-	err := c.client.Get(ctx, url, tableResponse)
+	err := c.get(ctx, p.backends, serviceTable, path, tableResponse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get table response from OSRM: %w", err)
+		if code, osrmErr := decodeOSRMError(err); osrmErr != nil {
+			errorsTotal.WithLabelValues(serviceTable, code).Inc()
+			return nil, osrmErr
+		}
+		errorsTotal.WithLabelValues(serviceTable, errorCodeTransport).Inc()
+		return nil, wrapTransportError(fmt.Errorf("failed to get table response from OSRM: %w", err))
 	}
 
 	if tableResponse.Code != CodeOk {
+		errorsTotal.WithLabelValues(serviceTable, tableResponse.Code).Inc()
 		return nil, handleOSRMError(tableResponse.Code, tableResponse.Message)
 	}
 
 	if isResponseUnexpected(tableResponse, len(destinations)) {
+		errorsTotal.WithLabelValues(serviceTable, errorCodeUnexpected).Inc()
 		var gotDurations int
 		if len(tableResponse.Durations) > 0 && len(tableResponse.Durations[0]) > 0 {
 			gotDurations = len(tableResponse.Durations[0])
@@ -108,21 +391,119 @@ func (c *OSRMClient) FindFastestRoutes(ctx context.Context, source service.Locat
 	}
 
 	durations, distances := tableResponse.Durations[0], tableResponse.Distances[0]
+	// Without a destinations parameter every coordinate is a destination, the source included
+	waypoints := tableResponse.Destinations
+	hasWaypoints := len(waypoints) == len(destinations)+1
 
 	for i, d := range destinations {
-		routes = append(routes, &service.Route{
-			Destination: d,
-			Distance:    distances[i+1],
-			Duration:    durations[i+1],
-		})
+		route := &service.Route{Destination: d}
+		if durations[i+1] == nil || distances[i+1] == nil {
+			route.Unreachable = true
+		} else {
+			route.Duration = *durations[i+1]
+			route.Distance = *distances[i+1]
+		}
+		if hasWaypoints {
+			route.SnappedLocation = waypoints[i+1].location()
+			route.SnapDistance = waypoints[i+1].Distance
+		}
+		routes = append(routes, route)
 	}
 
 	return routes, nil
 }
 
-func (c *OSRMClient) findNearestRoutesURL(source string, destinations []string) string {
+// get sends the request to the backends in the order given by the selection policy,
+// failing over to the next one when a backend is down, times out or answers with a server error.
+// With several backends each one only gets failoverAttempts tries, so that a dead one doesn't hold the request
+// for the whole retry loop of the HTTP client.
+func (c *OSRMClient) get(ctx context.Context, backends *backendPool, service, path string, response any) error {
+	candidates := backends.candidates()
+	get := c.client.Get
+	if len(candidates) > 1 {
+		get = func(ctx context.Context, url string, response any) error {
+			return c.client.GetWithAttempts(ctx, url, response, c.failoverAttempts)
+		}
+	}
+
+	var err error
+	for i, b := range candidates {
+		if i > 0 {
+			c.log.WithError(err).Warnf("failing over to OSRM backend %s", b.baseURL)
+		} else {
+			c.log.Debugf("using OSRM backend %s", b.baseURL)
+		}
+
+		trace.SpanFromContext(ctx).AddEvent("osrm.backend", trace.WithAttributes(
+			attribute.String("osrm.backend", b.baseURL),
+			attribute.Bool("osrm.failover", i > 0),
+		))
+
+		start := time.Now()
+		err = get(ctx, b.baseURL+path, response)
+		latency := time.Since(start)
+		requestDuration.WithLabelValues(service, b.baseURL).Observe(latency.Seconds())
+		if err == nil {
+			backends.onSuccess(b, latency)
+			return nil
+		}
+
+		if !shouldFailover(ctx, err) {
+			// The backend answered, the request itself is at fault
+			backends.onSuccess(b, latency)
+			return err
+		}
+		backends.onFailure(b)
+	}
+
+	return err
+}
+
+// shouldFailover tells whether another backend could succeed where this one failed
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *httpclient.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+func (c *OSRMClient) findNearestRoutesPath(profile, source string, destinations []string) string {
 	destinationsStr := strings.Join(destinations, ";")
-	return fmt.Sprintf("%s/table/v1/driving/%s;%s?sources=0&annotations=duration,distance", c.baseURL, source, destinationsStr)
+	return fmt.Sprintf("/%s/v1/%s/%s;%s?sources=0&annotations=duration,distance", serviceTable, profile, source, destinationsStr)
+}
+
+// decodeOSRMError extracts the OSRM error code from a non-200 response body, if there is one
+func decodeOSRMError(err error) (string, error) {
+	var statusErr *httpclient.StatusError
+	if !errors.As(err, &statusErr) {
+		return "", nil
+	}
+
+	tableResponse := &TableResponse{}
+	if jsonErr := json.Unmarshal(statusErr.Body, tableResponse); jsonErr != nil || tableResponse.Code == "" {
+		return "", nil
+	}
+
+	return tableResponse.Code, handleOSRMError(tableResponse.Code, tableResponse.Message)
+}
+
+// wrapTransportError marks failures to reach OSRM as service.ErrUnavailable. Client errors answered by a
+// backend and cancellations are left as they are.
+func wrapTransportError(err error) error {
+	var statusErr *httpclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode < http.StatusInternalServerError {
+		return err
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", service.ErrUnavailable, err)
 }
 
 func handleOSRMError(code, message string) error {
@@ -144,6 +525,10 @@ func handleOSRMError(code, message string) error {
 		baseErr = ErrNoSegment
 	case CodeTooBig:
 		baseErr = ErrTooBig
+	case CodeNoRoute:
+		baseErr = ErrNoRoute
+	case CodeNoTrips:
+		baseErr = ErrNoTrips
 	default:
 		if message != "" {
 			return fmt.Errorf("OSRM error [%s]: %s", code, message)
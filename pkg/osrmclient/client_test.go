@@ -0,0 +1,285 @@
+package osrmclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/httpclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTableServer serves table requests of any profile with the given handler and counts them
+func newTableServer(t *testing.T, handler func(coords []string) (int, any)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, coordsStr, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/table/v1/"), "/")
+		coords := strings.Split(coordsStr, ";")
+		status, body := handler(coords)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// latitudeTable answers with durations/distances equal to each coordinate's latitude,
+// so results can be matched back to their input
+func latitudeTable(coords []string) (int, any) {
+	row := make([]*float64, len(coords))
+	for i, c := range coords {
+		var v float64
+		var lon float64
+		fmt.Sscanf(c, "%g,%g", &lon, &v)
+		row[i] = &v
+	}
+	return http.StatusOK, &TableResponse{
+		Code:      CodeOk,
+		Durations: [][]*float64{row},
+		Distances: [][]*float64{row},
+	}
+}
+
+func newTestClient(t *testing.T, baseURL string, cfg Config) *OSRMClient {
+	t.Helper()
+	if len(cfg.BaseURLs) == 0 {
+		cfg.BaseURL = baseURL
+	}
+	cfg.HTTP = &httpclient.Config{
+		Log: logrus.New(),
+		RetryConfig: &httpclient.RetryConfig{
+			MaxRetries: 1,
+			BaseDelay:  10 * time.Millisecond,
+		},
+	}
+	client, err := NewOSRMClient(&cfg)
+	require.NoError(t, err)
+	return client
+}
+
+func buildDestinations(count int) []service.Location {
+	destinations := make([]service.Location, count)
+	for i := range destinations {
+		destinations[i] = service.Location(fmt.Sprintf("%d.5,13.4", i+1))
+	}
+	return destinations
+}
+
+func TestFindFastestRoutes_SingleRequest(t *testing.T) {
+	server, requests := newTableServer(t, latitudeTable)
+	client := newTestClient(t, server.URL, Config{})
+
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(3))
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+	require.Len(t, routes, 3)
+	for i, r := range routes {
+		assert.Equal(t, float64(i+1)+0.5, r.Duration)
+	}
+}
+
+func TestFindFastestRoutes_SnappedWaypoints(t *testing.T) {
+	server, _ := newTableServer(t, func(coords []string) (int, any) {
+		status, body := latitudeTable(coords)
+		resp := body.(*TableResponse)
+		for i := range coords {
+			resp.Destinations = append(resp.Destinations, Waypoint{
+				Distance: float64(i) * 10,
+				Location: [2]float64{13.5, float64(i) + 0.25},
+			})
+		}
+		return status, resp
+	})
+	client := newTestClient(t, server.URL, Config{})
+
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.Equal(t, service.Location("1.25,13.5"), routes[0].SnappedLocation)
+	assert.Equal(t, service.Location("2.25,13.5"), routes[1].SnappedLocation)
+	assert.Equal(t, 10.0, routes[0].SnapDistance)
+	assert.Equal(t, 20.0, routes[1].SnapDistance)
+}
+
+func TestFindFastestRoutes_Unreachable(t *testing.T) {
+	server, _ := newTableServer(t, func(coords []string) (int, any) {
+		status, body := latitudeTable(coords)
+		resp := body.(*TableResponse)
+		resp.Durations[0][2] = nil
+		resp.Distances[0][2] = nil
+		return status, resp
+	})
+	client := newTestClient(t, server.URL, Config{})
+
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.False(t, routes[0].Unreachable)
+	assert.Equal(t, 1.5, routes[0].Duration)
+	assert.True(t, routes[1].Unreachable)
+	assert.Zero(t, routes[1].Duration)
+	assert.Zero(t, routes[1].Distance)
+}
+
+func TestFindFastestRoutes_SendsLonLat(t *testing.T) {
+	var got []string
+	server, _ := newTableServer(t, func(coords []string) (int, any) {
+		got = coords
+		return latitudeTable(coords)
+	})
+	client := newTestClient(t, server.URL, Config{})
+
+	_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "52.517037,13.388860", []service.Location{"52.529407,13.397634"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"13.38886,52.517037", "13.397634,52.529407"}, got)
+}
+
+func TestFindFastestRoutes_Profiles(t *testing.T) {
+	var paths []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Host+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, body := latitudeTable([]string{"13.4,0.5", "13.4,1.5"})
+		json.NewEncoder(w).Encode(body)
+	})
+	driving := httptest.NewServer(handler)
+	t.Cleanup(driving.Close)
+	cycling := httptest.NewServer(handler)
+	t.Cleanup(cycling.Close)
+
+	client := newTestClient(t, driving.URL, Config{
+		Profiles: map[service.Profile]ProfileConfig{
+			service.ProfileCycling: {Name: "bike", BaseURLs: []string{cycling.URL}},
+		},
+	})
+
+	_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(1))
+	require.NoError(t, err)
+	_, err = client.FindFastestRoutes(context.Background(), service.ProfileCycling, "0.5,13.4", buildDestinations(1))
+	require.NoError(t, err)
+	_, err = client.FindFastestRoutes(context.Background(), service.ProfileWalking, "0.5,13.4", buildDestinations(1))
+	assert.ErrorIs(t, err, service.ErrUnsupportedProfile)
+
+	assert.Equal(t, []string{
+		strings.TrimPrefix(driving.URL, "http://") + "/table/v1/driving/13.4,0.5;13.4,1.5",
+		strings.TrimPrefix(cycling.URL, "http://") + "/table/v1/bike/13.4,0.5;13.4,1.5",
+	}, paths)
+}
+
+func TestNewOSRMClient_ProfileWithoutBaseURL(t *testing.T) {
+	_, err := NewOSRMClient(&Config{Profiles: map[service.Profile]ProfileConfig{service.ProfileWalking: {}}})
+
+	assert.Error(t, err)
+}
+
+func TestFindFastestRoutes_ChunksByCoordinates(t *testing.T) {
+	server, requests := newTableServer(t, latitudeTable)
+	client := newTestClient(t, server.URL, Config{MaxTableCoordinates: 4, MaxConcurrentChunks: 2})
+
+	destinations := buildDestinations(10)
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", destinations)
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), requests.Load())
+	require.Len(t, routes, len(destinations))
+	for i, r := range routes {
+		assert.Equal(t, destinations[i], r.Destination)
+		assert.Equal(t, float64(i+1)+0.5, r.Duration)
+	}
+}
+
+func TestFindFastestRoutes_ChunksByURLLength(t *testing.T) {
+	server, requests := newTableServer(t, latitudeTable)
+	client := newTestClient(t, server.URL, Config{})
+	client.maxURLLength = len(server.URL+client.findNearestRoutesPath("driving", "13.4,0.5", nil)) + 20
+
+	routes, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(5))
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Len(t, routes, 5)
+}
+
+func TestFindFastestRoutes_ChunkFailure(t *testing.T) {
+	server, _ := newTableServer(t, func(coords []string) (int, any) {
+		for _, c := range coords[1:] {
+			if c == "13.4,7.5" {
+				return http.StatusBadRequest, &TableResponse{Code: CodeNoSegment, Message: "Could not find a matching segment"}
+			}
+		}
+		return latitudeTable(coords)
+	})
+	client := newTestClient(t, server.URL, Config{MaxTableCoordinates: 4})
+
+	_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(10))
+
+	var chunkErr *ChunkError
+	require.ErrorAs(t, err, &chunkErr)
+	assert.Equal(t, 2, chunkErr.Chunk)
+	assert.Equal(t, 6, chunkErr.Start)
+	assert.Equal(t, 9, chunkErr.End)
+	assert.ErrorIs(t, err, ErrNoSegment)
+}
+
+func TestFindFastestRoutes_TooBig(t *testing.T) {
+	server, _ := newTableServer(t, func(coords []string) (int, any) {
+		return http.StatusBadRequest, &TableResponse{Code: CodeTooBig, Message: "Too many table coordinates"}
+	})
+	client := newTestClient(t, server.URL, Config{})
+
+	_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+	assert.ErrorIs(t, err, ErrTooBig)
+}
+
+func TestFindFastestRoutes_Unavailable(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		wantUnavailable bool
+	}{
+		{name: "server error", status: http.StatusBadGateway, wantUnavailable: true},
+		{name: "client error without OSRM code", status: http.StatusNotFound, wantUnavailable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTableServer(t, func(coords []string) (int, any) {
+				return tt.status, map[string]string{"error": "not OSRM"}
+			})
+			client := newTestClient(t, server.URL, Config{})
+
+			_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+			require.Error(t, err)
+			assert.Equal(t, tt.wantUnavailable, errors.Is(err, service.ErrUnavailable))
+		})
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		server, _ := newTableServer(t, latitudeTable)
+		server.Close()
+		client := newTestClient(t, server.URL, Config{})
+
+		_, err := client.FindFastestRoutes(context.Background(), service.ProfileDriving, "0.5,13.4", buildDestinations(2))
+
+		assert.ErrorIs(t, err, service.ErrUnavailable)
+	})
+}
@@ -0,0 +1,205 @@
+package osrmclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/errgroup"
+)
+
+// MatrixChunkError wraps a failure of one table request when the matrix was split into chunks.
+// The indices are 0-based and end exclusive.
+type MatrixChunkError struct {
+	SourceStart      int
+	SourceEnd        int
+	DestinationStart int
+	DestinationEnd   int
+	Err              error
+}
+
+func (e *MatrixChunkError) Error() string {
+	return fmt.Sprintf("matrix chunk of sources [%d, %d) and destinations [%d, %d) failed: %v",
+		e.SourceStart, e.SourceEnd, e.DestinationStart, e.DestinationEnd, e.Err)
+}
+
+func (e *MatrixChunkError) Unwrap() error {
+	return e.Err
+}
+
+// FindMatrix returns the routes from every source to every destination.
+// Matrices that don't fit in one table request are split by sources and by destinations,
+// and the chunks are queried concurrently.
+func (c *OSRMClient) FindMatrix(ctx context.Context, travelProfile service.Profile, sources, destinations []service.Location) (matrix *service.Matrix, err error) {
+	ctx, span := tracer.Start(ctx, "OSRMClient.FindMatrix")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	p, ok := c.profiles[travelProfile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", service.ErrUnsupportedProfile, travelProfile)
+	}
+
+	sourceCoords, err := osrmCoordinates(sources)
+	if err != nil {
+		return nil, err
+	}
+	destinationCoords, err := osrmCoordinates(destinations)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceChunks, destinationChunks := c.chunkMatrix(p, sourceCoords, destinationCoords)
+	chunks := 0
+	for _, dcs := range destinationChunks {
+		chunks += len(dcs)
+	}
+	span.SetAttributes(
+		attribute.String("osrm.profile", p.name),
+		attribute.Int("osrm.sources", len(sources)),
+		attribute.Int("osrm.destinations", len(destinations)),
+		attribute.Int("osrm.chunks", chunks),
+	)
+
+	matrix = &service.Matrix{
+		Sources:      sources,
+		Destinations: destinations,
+	}
+	if chunks == 1 {
+		if matrix.Cells, err = c.findTableMatrix(ctx, p, sourceCoords, destinationCoords); err != nil {
+			return nil, err
+		}
+		return matrix, nil
+	}
+
+	c.log.Debugf("splitting a %dx%d matrix into %d table requests", len(sources), len(destinations), chunks)
+
+	matrix.Cells = make([][]service.MatrixCell, len(sources))
+	for i := range matrix.Cells {
+		matrix.Cells[i] = make([]service.MatrixCell, len(destinations))
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(c.maxConcurrentChunks)
+	for i, sc := range sourceChunks {
+		for _, dc := range destinationChunks[i] {
+			g.Go(func() error {
+				cells, err := c.findTableMatrix(gCtx, p, sourceCoords[sc.start:sc.end], destinationCoords[dc.start:dc.end])
+				if err != nil {
+					return &MatrixChunkError{SourceStart: sc.start, SourceEnd: sc.end, DestinationStart: dc.start, DestinationEnd: dc.end, Err: err}
+				}
+				// Chunks write to disjoint cells
+				for si, row := range cells {
+					copy(matrix.Cells[sc.start+si][dc.start:dc.end], row)
+				}
+				return nil
+			})
+		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return matrix, nil
+}
+
+// chunkMatrix splits the sources, then the destinations of each source chunk, so that every table request
+// respects both the coordinate and URL length limits. destinationChunks[i] belongs to sourceChunks[i].
+func (c *OSRMClient) chunkMatrix(p *profile, sources, destinations []string) ([]chunk, [][]chunk) {
+	baseLength := p.backends.maxBaseURLLength() + len(c.matrixPath(p.name, nil, nil))
+	// Every coordinate also adds its index to the sources or destinations parameter
+	indexLength := len(strconv.Itoa(c.maxTableCoordinates)) + 1
+
+	// Sources get at most half of the coordinates and URL length, unless there are few destinations
+	maxSources := c.maxTableCoordinates - min(len(destinations), c.maxTableCoordinates/2)
+	sourceChunks := packChunks(sources, maxSources, baseLength, baseLength+(c.maxURLLength-baseLength)/2, indexLength)
+
+	destinationChunks := make([][]chunk, len(sourceChunks))
+	for i, sc := range sourceChunks {
+		length := baseLength
+		for _, s := range sources[sc.start:sc.end] {
+			length += len(s) + 1 + indexLength
+		}
+		destinationChunks[i] = packChunks(destinations, c.maxTableCoordinates-(sc.end-sc.start), length, c.maxURLLength, indexLength)
+	}
+
+	return sourceChunks, destinationChunks
+}
+
+// findTableMatrix sends one table request, coordinates are already in OSRM's longitude,latitude order
+func (c *OSRMClient) findTableMatrix(ctx context.Context, p *profile, sources, destinations []string) ([][]service.MatrixCell, error) {
+	tableResponse := &TableResponse{}
+	if err := c.get(ctx, p.backends, serviceTable, c.matrixPath(p.name, sources, destinations), tableResponse); err != nil {
+		if code, osrmErr := decodeOSRMError(err); osrmErr != nil {
+			errorsTotal.WithLabelValues(serviceTable, code).Inc()
+			return nil, osrmErr
+		}
+		errorsTotal.WithLabelValues(serviceTable, errorCodeTransport).Inc()
+		return nil, wrapTransportError(fmt.Errorf("failed to get table response from OSRM: %w", err))
+	}
+
+	if tableResponse.Code != CodeOk {
+		errorsTotal.WithLabelValues(serviceTable, tableResponse.Code).Inc()
+		return nil, handleOSRMError(tableResponse.Code, tableResponse.Message)
+	}
+
+	if isMatrixUnexpected(tableResponse, len(sources), len(destinations)) {
+		errorsTotal.WithLabelValues(serviceTable, errorCodeUnexpected).Inc()
+		return nil, fmt.Errorf("%w: expected a %dx%d matrix", ErrUnexpected, len(sources), len(destinations))
+	}
+
+	cells := make([][]service.MatrixCell, len(sources))
+	for i := range cells {
+		cells[i] = make([]service.MatrixCell, len(destinations))
+		for j := range cells[i] {
+			duration, distance := tableResponse.Durations[i][j], tableResponse.Distances[i][j]
+			if duration == nil || distance == nil {
+				cells[i][j].Unreachable = true
+				continue
+			}
+			cells[i][j].Duration = *duration
+			cells[i][j].Distance = *distance
+		}
+	}
+
+	return cells, nil
+}
+
+// matrixPath lists the sources first, then the destinations
+func (c *OSRMClient) matrixPath(profile string, sources, destinations []string) string {
+	sourceIndices := make([]string, len(sources))
+	for i := range sources {
+		sourceIndices[i] = strconv.Itoa(i)
+	}
+	destinationIndices := make([]string, len(destinations))
+	for i := range destinations {
+		destinationIndices[i] = strconv.Itoa(len(sources) + i)
+	}
+
+	return fmt.Sprintf("/%s/v1/%s/%s?sources=%s&destinations=%s&annotations=duration,distance",
+		serviceTable, profile, strings.Join(append(slices.Clone(sources), destinations...), ";"),
+		strings.Join(sourceIndices, ";"), strings.Join(destinationIndices, ";"))
+}
+
+func isMatrixUnexpected(tableResponse *TableResponse, sources, destinations int) bool {
+	if len(tableResponse.Durations) != sources || len(tableResponse.Distances) != sources {
+		return true
+	}
+	for i := range sources {
+		if len(tableResponse.Durations[i]) != destinations || len(tableResponse.Distances[i]) != destinations {
+			return true
+		}
+	}
+	return false
+}
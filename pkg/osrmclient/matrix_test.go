@@ -0,0 +1,129 @@
+package osrmclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMatrixServer answers table requests with a duration of 1000 times the source latitude plus the
+// destination latitude, and records the number of coordinates of every request
+func newMatrixServer(t *testing.T, unreachable func(src, dst float64) bool) (*httptest.Server, func() []int) {
+	t.Helper()
+	var mu sync.Mutex
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, coordsStr, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/table/v1/"), "/")
+		coords := strings.Split(coordsStr, ";")
+		mu.Lock()
+		sizes = append(sizes, len(coords))
+		mu.Unlock()
+
+		latitude := func(index string) float64 {
+			i, _ := strconv.Atoi(index)
+			var lon, lat float64
+			fmt.Sscanf(coords[i], "%g,%g", &lon, &lat)
+			return lat
+		}
+
+		// url.Values drops parameters containing ";", which OSRM uses to separate indices
+		params := make(map[string][]string)
+		for _, param := range strings.Split(r.URL.RawQuery, "&") {
+			key, value, _ := strings.Cut(param, "=")
+			params[key] = strings.Split(value, ";")
+		}
+
+		resp := &TableResponse{Code: CodeOk}
+		for _, s := range params["sources"] {
+			durations := make([]*float64, 0)
+			for _, d := range params["destinations"] {
+				src, dst := latitude(s), latitude(d)
+				if unreachable != nil && unreachable(src, dst) {
+					durations = append(durations, nil)
+					continue
+				}
+				v := src*1000 + dst
+				durations = append(durations, &v)
+			}
+			resp.Durations = append(resp.Durations, durations)
+			resp.Distances = append(resp.Distances, durations)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return sizes
+	}
+}
+
+func buildLocations(from, count int) []service.Location {
+	locations := make([]service.Location, count)
+	for i := range locations {
+		locations[i] = service.Location(fmt.Sprintf("%d,13.4", from+i))
+	}
+	return locations
+}
+
+func TestFindMatrix_SingleRequest(t *testing.T) {
+	server, sizes := newMatrixServer(t, func(src, dst float64) bool { return src == 2 && dst == 11 })
+	client := newTestClient(t, server.URL, Config{})
+
+	matrix, err := client.FindMatrix(context.Background(), service.ProfileDriving, buildLocations(1, 2), buildLocations(10, 3))
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{5}, sizes())
+	require.Len(t, matrix.Cells, 2)
+	assert.Equal(t, []service.MatrixCell{
+		{Duration: 2010, Distance: 2010},
+		{Unreachable: true},
+		{Duration: 2012, Distance: 2012},
+	}, matrix.Cells[1])
+}
+
+func TestFindMatrix_ChunksBothDimensions(t *testing.T) {
+	server, sizes := newMatrixServer(t, nil)
+	client := newTestClient(t, server.URL, Config{MaxTableCoordinates: 6, MaxConcurrentChunks: 3})
+
+	sources, destinations := buildLocations(1, 7), buildLocations(10, 8)
+	matrix, err := client.FindMatrix(context.Background(), service.ProfileDriving, sources, destinations)
+
+	require.NoError(t, err)
+	assert.Greater(t, len(sizes()), 1)
+	for _, size := range sizes() {
+		assert.LessOrEqual(t, size, 6)
+	}
+	require.Len(t, matrix.Cells, len(sources))
+	for i := range sources {
+		require.Len(t, matrix.Cells[i], len(destinations))
+		for j := range destinations {
+			assert.Equal(t, float64((i+1)*1000+10+j), matrix.Cells[i][j].Duration)
+		}
+	}
+}
+
+func TestFindMatrix_ChunkFailure(t *testing.T) {
+	server, _ := newTableServer(t, func(coords []string) (int, any) {
+		return http.StatusBadRequest, &TableResponse{Code: CodeNoSegment, Message: "Could not find a matching segment"}
+	})
+	client := newTestClient(t, server.URL, Config{MaxTableCoordinates: 4})
+
+	_, err := client.FindMatrix(context.Background(), service.ProfileDriving, buildLocations(1, 3), buildLocations(10, 3))
+
+	var chunkErr *MatrixChunkError
+	require.ErrorAs(t, err, &chunkErr)
+	assert.ErrorIs(t, err, ErrNoSegment)
+}
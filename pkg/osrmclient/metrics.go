@@ -0,0 +1,36 @@
+package osrmclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// errorCodeTransport labels failures where OSRM didn't answer with an error code (network, 5xx, circuit open)
+	errorCodeTransport = "Transport"
+	// errorCodeUnexpected labels answers that don't have the expected structure
+	errorCodeUnexpected = "Unexpected"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "osrm_request_duration_seconds",
+		Help:    "Latency of OSRM requests by OSRM service and backend, failovers included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "backend"})
+
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "osrm_errors_total",
+		Help: "Number of failed OSRM requests by OSRM service and error code.",
+	}, []string{"service", "code"})
+
+	backendRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "osrm_backend_requests_total",
+		Help: "Number of requests sent to each OSRM backend by outcome (success, failure).",
+	}, []string{"backend", "outcome"})
+
+	backendHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "osrm_backend_healthy",
+		Help: "Whether the OSRM backend is considered healthy (1) or ejected after consecutive failures (0).",
+	}, []string{"backend"})
+)
@@ -6,9 +6,33 @@ import (
 )
 
 type MockOSRMClient struct {
-	FindFastestRoutesFunc func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error)
+	FindFastestRoutesFunc func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error)
+	FindMatrixFunc        func(ctx context.Context, profile service.Profile, sources, destinations []service.Location) (*service.Matrix, error)
+	FindRouteFunc         func(ctx context.Context, profile service.Profile, source, destination service.Location, geometry service.GeometryFormat, steps bool) (*service.RouteDetails, error)
+	FindTripFunc          func(ctx context.Context, profile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (*service.Trip, error)
+	// PingFunc is optional, Ping succeeds when it is nil
+	PingFunc func(ctx context.Context) error
 }
 
-func (m *MockOSRMClient) FindFastestRoutes(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
-	return m.FindFastestRoutesFunc(ctx, source, destinations)
-}
\ No newline at end of file
+func (m *MockOSRMClient) Ping(ctx context.Context) error {
+	if m.PingFunc == nil {
+		return nil
+	}
+	return m.PingFunc(ctx)
+}
+
+func (m *MockOSRMClient) FindFastestRoutes(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+	return m.FindFastestRoutesFunc(ctx, profile, source, destinations)
+}
+
+func (m *MockOSRMClient) FindMatrix(ctx context.Context, profile service.Profile, sources, destinations []service.Location) (*service.Matrix, error) {
+	return m.FindMatrixFunc(ctx, profile, sources, destinations)
+}
+
+func (m *MockOSRMClient) FindRoute(ctx context.Context, profile service.Profile, source, destination service.Location, geometry service.GeometryFormat, steps bool) (*service.RouteDetails, error) {
+	return m.FindRouteFunc(ctx, profile, source, destination, geometry, steps)
+}
+
+func (m *MockOSRMClient) FindTrip(ctx context.Context, profile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (*service.Trip, error) {
+	return m.FindTripFunc(ctx, profile, source, stops, end, roundtrip)
+}
@@ -0,0 +1,204 @@
+package osrmclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type RouteResponse struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message,omitempty"`
+	Routes  []RouteItem `json:"routes"`
+}
+
+type RouteItem struct {
+	Distance float64 `json:"distance"`
+	Duration float64 `json:"duration"`
+	// Geometry is an encoded polyline string or a GeoJSON LineString object, depending on the geometries parameter
+	Geometry json.RawMessage `json:"geometry"`
+	Legs     []RouteLeg      `json:"legs"`
+}
+
+type RouteLeg struct {
+	Distance float64     `json:"distance"`
+	Duration float64     `json:"duration"`
+	Steps    []RouteStep `json:"steps"`
+}
+
+type RouteStep struct {
+	Distance float64  `json:"distance"`
+	Duration float64  `json:"duration"`
+	Name     string   `json:"name"`
+	Mode     string   `json:"mode"`
+	Maneuver Maneuver `json:"maneuver"`
+}
+
+type Maneuver struct {
+	Type     string `json:"type"`
+	Modifier string `json:"modifier,omitempty"`
+	// Exit is the roundabout exit to take, 0 if not applicable
+	Exit int `json:"exit,omitempty"`
+	// Location is the coordinate of the maneuver as [longitude, latitude]
+	Location [2]float64 `json:"location"`
+}
+
+type lineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+// FindRoute returns the full route from source to destination using the OSRM route service
+func (c *OSRMClient) FindRoute(ctx context.Context, travelProfile service.Profile, source, destination service.Location, geometry service.GeometryFormat, steps bool) (route *service.RouteDetails, err error) {
+	ctx, span := tracer.Start(ctx, "OSRMClient.FindRoute")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	p, ok := c.profiles[travelProfile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", service.ErrUnsupportedProfile, travelProfile)
+	}
+	span.SetAttributes(attribute.String("osrm.profile", p.name))
+
+	coords, err := osrmCoordinates([]service.Location{source, destination})
+	if err != nil {
+		return nil, err
+	}
+
+	routeResponse := &RouteResponse{}
+	if err := c.get(ctx, p.backends, serviceRoute, c.routePath(p.name, coords, geometry, steps), routeResponse); err != nil {
+		if code, osrmErr := decodeOSRMError(err); osrmErr != nil {
+			errorsTotal.WithLabelValues(serviceRoute, code).Inc()
+			return nil, wrapServiceError(osrmErr)
+		}
+		errorsTotal.WithLabelValues(serviceRoute, errorCodeTransport).Inc()
+		return nil, wrapTransportError(fmt.Errorf("failed to get route response from OSRM: %w", err))
+	}
+
+	if routeResponse.Code != CodeOk {
+		errorsTotal.WithLabelValues(serviceRoute, routeResponse.Code).Inc()
+		return nil, wrapServiceError(handleOSRMError(routeResponse.Code, routeResponse.Message))
+	}
+
+	if len(routeResponse.Routes) == 0 {
+		errorsTotal.WithLabelValues(serviceRoute, errorCodeUnexpected).Inc()
+		return nil, fmt.Errorf("%w: no route in response", ErrUnexpected)
+	}
+
+	item := routeResponse.Routes[0]
+	route = &service.RouteDetails{
+		Distance: item.Distance,
+		Duration: item.Duration,
+	}
+
+	if route.Geometry, err = decodeGeometry(item.Geometry, geometry); err != nil {
+		errorsTotal.WithLabelValues(serviceRoute, errorCodeUnexpected).Inc()
+		return nil, err
+	}
+
+	if steps {
+		route.Steps = make([]service.RouteStep, 0)
+		for _, leg := range item.Legs {
+			for _, step := range leg.Steps {
+				route.Steps = append(route.Steps, service.RouteStep{
+					Instruction: instruction(step),
+					Name:        step.Name,
+					Distance:    step.Distance,
+					Duration:    step.Duration,
+					Maneuver:    step.Maneuver.Type,
+					Modifier:    step.Maneuver.Modifier,
+					Location:    service.Coordinate{Lat: step.Maneuver.Location[1], Lon: step.Maneuver.Location[0]},
+				})
+			}
+		}
+	}
+
+	return route, nil
+}
+
+func (c *OSRMClient) routePath(profile string, coords []string, geometry service.GeometryFormat, steps bool) string {
+	geometries := "polyline"
+	if geometry == service.GeometryGeoJSON {
+		geometries = "geojson"
+	}
+
+	return fmt.Sprintf("/%s/v1/%s/%s?overview=full&geometries=%s&steps=%t",
+		serviceRoute, profile, strings.Join(coords, ";"), geometries, steps)
+}
+
+// wrapServiceError marks OSRM errors the service defines an equivalent for, so callers don't depend on the OSRM client
+func wrapServiceError(err error) error {
+	switch {
+	case errors.Is(err, ErrNoRoute):
+		return fmt.Errorf("%w: %w", service.ErrNoRoute, err)
+	case errors.Is(err, ErrNoTrips):
+		return fmt.Errorf("%w: %w", service.ErrNoTrip, err)
+	default:
+		return err
+	}
+}
+
+func decodeGeometry(raw json.RawMessage, format service.GeometryFormat) (service.Geometry, error) {
+	if format == service.GeometryGeoJSON {
+		line := &lineString{}
+		if err := json.Unmarshal(raw, line); err != nil || line.Type != "LineString" {
+			return service.Geometry{}, fmt.Errorf("%w: geometry is not a GeoJSON LineString", ErrUnexpected)
+		}
+		coordinates := make([]service.Coordinate, len(line.Coordinates))
+		for i, c := range line.Coordinates {
+			coordinates[i] = service.Coordinate{Lat: c[1], Lon: c[0]}
+		}
+		return service.Geometry{Coordinates: coordinates}, nil
+	}
+
+	var polyline string
+	if err := json.Unmarshal(raw, &polyline); err != nil {
+		return service.Geometry{}, fmt.Errorf("%w: geometry is not an encoded polyline", ErrUnexpected)
+	}
+	return service.Geometry{Polyline: polyline}, nil
+}
+
+// instruction builds a short English instruction from an OSRM step, e.g. "Turn left onto Unter den Linden"
+func instruction(step RouteStep) string {
+	m := step.Maneuver
+
+	var text string
+	switch m.Type {
+	case "depart":
+		text = "Depart"
+	case "arrive":
+		return "Arrive at your destination"
+	case "roundabout", "rotary":
+		text = "Enter the roundabout"
+		if m.Exit > 0 {
+			text += " and take exit " + strconv.Itoa(m.Exit)
+		}
+	case "new name", "continue":
+		text = "Continue"
+	case "end of road", "fork", "turn", "on ramp", "off ramp", "merge":
+		text = strings.ToUpper(m.Type[:1]) + m.Type[1:]
+	default:
+		text = "Continue"
+	}
+
+	if m.Modifier != "" && m.Type != "roundabout" && m.Type != "rotary" {
+		text += " " + m.Modifier
+	}
+	if step.Name != "" {
+		text += " onto " + step.Name
+	}
+
+	return text
+}
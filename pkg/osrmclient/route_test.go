@@ -0,0 +1,83 @@
+package osrmclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouteServer(t *testing.T, status int, body string) (*httptest.Server, *url.URL) {
+	t.Helper()
+	var requested url.URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = *r.URL
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requested
+}
+
+func TestFindRoute_Polyline(t *testing.T) {
+	server, requested := newRouteServer(t, http.StatusOK, `{"code":"Ok","routes":[{"distance":1884.7,"duration":260.3,"geometry":"ofp_Ik_vpAilAyu@","legs":[]}]}`)
+	client := newTestClient(t, server.URL, Config{})
+
+	route, err := client.FindRoute(context.Background(), service.ProfileDriving, "52.517037,13.38886", "52.529407,13.397634", service.GeometryPolyline, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, "/route/v1/driving/13.38886,52.517037;13.397634,52.529407", requested.Path)
+	assert.Equal(t, "polyline", requested.Query().Get("geometries"))
+	assert.Equal(t, "false", requested.Query().Get("steps"))
+	assert.Equal(t, &service.RouteDetails{
+		Distance: 1884.7,
+		Duration: 260.3,
+		Geometry: service.Geometry{Polyline: "ofp_Ik_vpAilAyu@"},
+	}, route)
+}
+
+func TestFindRoute_GeoJSONWithSteps(t *testing.T) {
+	response := &RouteResponse{Code: CodeOk, Routes: []RouteItem{{
+		Distance: 1884.7,
+		Duration: 260.3,
+		Geometry: json.RawMessage(`{"type":"LineString","coordinates":[[13.38886,52.517037],[13.397634,52.529407]]}`),
+		Legs: []RouteLeg{{Steps: []RouteStep{
+			{Distance: 1000, Duration: 100, Name: "Unter den Linden", Maneuver: Maneuver{Type: "depart", Location: [2]float64{13.38886, 52.517037}}},
+			{Distance: 884.7, Duration: 160.3, Name: "Friedrichstraße", Maneuver: Maneuver{Type: "turn", Modifier: "left", Location: [2]float64{13.3889, 52.5171}}},
+			{Distance: 0, Duration: 0, Maneuver: Maneuver{Type: "arrive", Location: [2]float64{13.397634, 52.529407}}},
+		}}},
+	}}}
+	body, err := json.Marshal(response)
+	require.NoError(t, err)
+	server, requested := newRouteServer(t, http.StatusOK, string(body))
+	client := newTestClient(t, server.URL, Config{})
+
+	route, err := client.FindRoute(context.Background(), service.ProfileDriving, "52.517037,13.38886", "52.529407,13.397634", service.GeometryGeoJSON, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "geojson", requested.Query().Get("geometries"))
+	assert.Equal(t, "true", requested.Query().Get("steps"))
+	assert.Equal(t, []service.Coordinate{{Lat: 52.517037, Lon: 13.38886}, {Lat: 52.529407, Lon: 13.397634}}, route.Geometry.Coordinates)
+	require.Len(t, route.Steps, 3)
+	assert.Equal(t, "Depart onto Unter den Linden", route.Steps[0].Instruction)
+	assert.Equal(t, "Turn left onto Friedrichstraße", route.Steps[1].Instruction)
+	assert.Equal(t, service.Coordinate{Lat: 52.5171, Lon: 13.3889}, route.Steps[1].Location)
+	assert.Equal(t, "Arrive at your destination", route.Steps[2].Instruction)
+}
+
+func TestFindRoute_NoRoute(t *testing.T) {
+	server, _ := newRouteServer(t, http.StatusBadRequest, `{"code":"NoRoute","message":"Impossible route between points"}`)
+	client := newTestClient(t, server.URL, Config{})
+
+	_, err := client.FindRoute(context.Background(), service.ProfileDriving, "52.517037,13.38886", "40.7128,-74.006", service.GeometryPolyline, false)
+
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.ErrorIs(t, err, service.ErrNoRoute)
+}
@@ -0,0 +1,148 @@
+package osrmclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type TripResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message,omitempty"`
+	Waypoints []TripWaypoint `json:"waypoints"`
+	Trips     []TripItem     `json:"trips"`
+}
+
+// TripWaypoint is an input coordinate of a trip, in input order
+type TripWaypoint struct {
+	Waypoint
+	// WaypointIndex is the position of the coordinate in its trip
+	WaypointIndex int `json:"waypoint_index"`
+	// TripsIndex is the trip the coordinate belongs to, OSRM splits coordinates that aren't connected into several trips
+	TripsIndex int `json:"trips_index"`
+}
+
+type TripItem struct {
+	Distance float64    `json:"distance"`
+	Duration float64    `json:"duration"`
+	Legs     []RouteLeg `json:"legs"`
+}
+
+// FindTrip returns the fastest order to visit the stops in using the OSRM trip service.
+// The trip starts at source and ends at end if not empty, back at source if roundtrip, else at any stop.
+func (c *OSRMClient) FindTrip(ctx context.Context, travelProfile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (trip *service.Trip, err error) {
+	ctx, span := tracer.Start(ctx, "OSRMClient.FindTrip")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	p, ok := c.profiles[travelProfile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", service.ErrUnsupportedProfile, travelProfile)
+	}
+	span.SetAttributes(
+		attribute.String("osrm.profile", p.name),
+		attribute.Int("osrm.stops", len(stops)),
+	)
+
+	locations := append([]service.Location{source}, stops...)
+	if end != "" {
+		locations = append(locations, end)
+		roundtrip = false
+	}
+	coords, err := osrmCoordinates(locations)
+	if err != nil {
+		return nil, err
+	}
+
+	tripResponse := &TripResponse{}
+	if err := c.get(ctx, p.backends, serviceTrip, c.tripPath(p.name, coords, roundtrip, end != ""), tripResponse); err != nil {
+		if code, osrmErr := decodeOSRMError(err); osrmErr != nil {
+			errorsTotal.WithLabelValues(serviceTrip, code).Inc()
+			return nil, wrapServiceError(osrmErr)
+		}
+		errorsTotal.WithLabelValues(serviceTrip, errorCodeTransport).Inc()
+		return nil, wrapTransportError(fmt.Errorf("failed to get trip response from OSRM: %w", err))
+	}
+
+	if tripResponse.Code != CodeOk {
+		errorsTotal.WithLabelValues(serviceTrip, tripResponse.Code).Inc()
+		return nil, wrapServiceError(handleOSRMError(tripResponse.Code, tripResponse.Message))
+	}
+
+	if len(tripResponse.Trips) > 1 {
+		errorsTotal.WithLabelValues(serviceTrip, CodeNoTrips).Inc()
+		return nil, wrapServiceError(fmt.Errorf("%w: stops are split into %d unconnected trips", ErrNoTrips, len(tripResponse.Trips)))
+	}
+
+	legs := len(locations) - 1
+	if roundtrip {
+		legs++
+	}
+	// visits[i] is the index in locations of the i-th location visited
+	visits, ok := tripVisits(tripResponse, len(locations), legs)
+	if !ok {
+		errorsTotal.WithLabelValues(serviceTrip, errorCodeUnexpected).Inc()
+		return nil, fmt.Errorf("%w: expected one trip through %d locations", ErrUnexpected, len(locations))
+	}
+
+	item := tripResponse.Trips[0]
+	trip = &service.Trip{
+		Order:    make([]int, 0, len(stops)),
+		Legs:     make([]service.TripLeg, legs),
+		Distance: item.Distance,
+		Duration: item.Duration,
+	}
+	for _, v := range visits[1 : len(stops)+1] {
+		trip.Order = append(trip.Order, v-1)
+	}
+	for i, leg := range item.Legs {
+		trip.Legs[i] = service.TripLeg{
+			From:     locations[visits[i]],
+			To:       locations[visits[(i+1)%len(visits)]],
+			Distance: leg.Distance,
+			Duration: leg.Duration,
+		}
+	}
+
+	return trip, nil
+}
+
+func (c *OSRMClient) tripPath(profile string, coords []string, roundtrip, fixedEnd bool) string {
+	destination := "any"
+	if fixedEnd {
+		destination = "last"
+	}
+
+	return fmt.Sprintf("/%s/v1/%s/%s?roundtrip=%t&source=first&destination=%s&overview=false",
+		serviceTrip, profile, strings.Join(coords, ";"), roundtrip, destination)
+}
+
+// tripVisits inverts the waypoint indices of a single trip response into the visiting order,
+// it fails if the response doesn't describe one trip through all locations starting at the first one
+func tripVisits(tripResponse *TripResponse, locations, legs int) ([]int, bool) {
+	if len(tripResponse.Trips) != 1 || len(tripResponse.Trips[0].Legs) != legs || len(tripResponse.Waypoints) != locations {
+		return nil, false
+	}
+
+	visits := make([]int, locations)
+	for i := range visits {
+		visits[i] = -1
+	}
+	for i, w := range tripResponse.Waypoints {
+		if w.TripsIndex != 0 || w.WaypointIndex < 0 || w.WaypointIndex >= locations || visits[w.WaypointIndex] != -1 {
+			return nil, false
+		}
+		visits[w.WaypointIndex] = i
+	}
+
+	return visits, visits[0] == 0
+}
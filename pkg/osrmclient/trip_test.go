@@ -0,0 +1,102 @@
+package osrmclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tripWaypoints(indices ...int) []TripWaypoint {
+	waypoints := make([]TripWaypoint, len(indices))
+	for i, index := range indices {
+		waypoints[i] = TripWaypoint{WaypointIndex: index}
+	}
+	return waypoints
+}
+
+func TestFindTrip_Roundtrip(t *testing.T) {
+	// The stops are visited in the order 2, 0, 1
+	body, err := json.Marshal(&TripResponse{
+		Code:      CodeOk,
+		Waypoints: tripWaypoints(0, 2, 3, 1),
+		Trips: []TripItem{{
+			Distance: 100,
+			Duration: 10,
+			Legs:     []RouteLeg{{Distance: 10, Duration: 1}, {Distance: 20, Duration: 2}, {Distance: 30, Duration: 3}, {Distance: 40, Duration: 4}},
+		}},
+	})
+	require.NoError(t, err)
+	server, requested := newRouteServer(t, http.StatusOK, string(body))
+	client := newTestClient(t, server.URL, Config{})
+
+	trip, err := client.FindTrip(context.Background(), service.ProfileDriving, "1,13.4", []service.Location{"10,13.4", "11,13.4", "12,13.4"}, "", true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "/trip/v1/driving/13.4,1;13.4,10;13.4,11;13.4,12", requested.Path)
+	assert.Equal(t, "true", requested.Query().Get("roundtrip"))
+	assert.Equal(t, "first", requested.Query().Get("source"))
+	assert.Equal(t, []int{2, 0, 1}, trip.Order)
+	assert.Equal(t, []service.TripLeg{
+		{From: "1,13.4", To: "12,13.4", Distance: 10, Duration: 1},
+		{From: "12,13.4", To: "10,13.4", Distance: 20, Duration: 2},
+		{From: "10,13.4", To: "11,13.4", Distance: 30, Duration: 3},
+		{From: "11,13.4", To: "1,13.4", Distance: 40, Duration: 4},
+	}, trip.Legs)
+	assert.Equal(t, 100.0, trip.Distance)
+}
+
+func TestFindTrip_FixedEnd(t *testing.T) {
+	body, err := json.Marshal(&TripResponse{
+		Code:      CodeOk,
+		Waypoints: tripWaypoints(0, 2, 1, 3),
+		Trips:     []TripItem{{Legs: []RouteLeg{{}, {}, {}}}},
+	})
+	require.NoError(t, err)
+	server, requested := newRouteServer(t, http.StatusOK, string(body))
+	client := newTestClient(t, server.URL, Config{})
+
+	trip, err := client.FindTrip(context.Background(), service.ProfileDriving, "1,13.4", []service.Location{"10,13.4", "11,13.4"}, "20,13.4", true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "false", requested.Query().Get("roundtrip"))
+	assert.Equal(t, "last", requested.Query().Get("destination"))
+	assert.Equal(t, []int{1, 0}, trip.Order)
+	require.Len(t, trip.Legs, 3)
+	assert.Equal(t, service.Location("20,13.4"), trip.Legs[2].To)
+}
+
+func TestFindTrip_NoTrips(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{
+			name:   "NoTrips error",
+			status: http.StatusBadRequest,
+			body:   `{"code":"NoTrips","message":"No trip visiting all destinations possible."}`,
+		},
+		{
+			name:   "unconnected stops",
+			status: http.StatusOK,
+			body:   `{"code":"Ok","waypoints":[{"waypoint_index":0,"trips_index":0},{"waypoint_index":0,"trips_index":1}],"trips":[{"legs":[{}]},{"legs":[{}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newRouteServer(t, tt.status, tt.body)
+			client := newTestClient(t, server.URL, Config{})
+
+			_, err := client.FindTrip(context.Background(), service.ProfileDriving, "1,13.4", []service.Location{"10,13.4"}, "", true)
+
+			assert.ErrorIs(t, err, ErrNoTrips)
+			assert.ErrorIs(t, err, service.ErrNoTrip)
+		})
+	}
+}
@@ -0,0 +1,360 @@
+// Package planner assigns stops to vehicles and orders them, given the travel durations between all locations.
+//
+// Routes are built with a cheapest insertion heuristic and improved with a randomized local search
+// (relocating stops and reversing route segments). The output only depends on the problem and the seed.
+package planner
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+const defaultIterations = 2000
+
+// epsilon keeps the local search from cycling on moves that only differ by rounding errors
+const epsilon = 1e-9
+
+var ErrInvalidProblem = errors.New("invalid planning problem")
+
+// Problem refers to locations by their index in Durations and Distances. Times are in seconds,
+// relative to any epoch shared by all shifts and time windows, e.g. the start of the day.
+type Problem struct {
+	// Durations[i][j] is the travel time from location i to location j, +Inf if j can't be reached from i
+	Durations [][]float64
+	// Distances[i][j] is the travel distance in meters from location i to location j
+	Distances [][]float64
+	Vehicles  []Vehicle
+	Stops     []Stop
+}
+
+type Vehicle struct {
+	// Start and End are the locations of the depots the vehicle leaves from and returns to
+	Start int
+	End   int
+	// Capacity is the max total demand of the stops on the route, 0 means unlimited
+	Capacity float64
+	// Shift bounds the time the vehicle leaves its start depot and returns to its end depot
+	Shift Window
+}
+
+type Stop struct {
+	Location int
+	Demand   float64
+	// Window bounds the time the service at the stop may begin
+	Window      Window
+	ServiceTime float64
+}
+
+// Window is a time interval, an End of 0 means no upper bound
+type Window struct {
+	Start float64
+	End   float64
+}
+
+func (w Window) end() float64 {
+	if w.End == 0 {
+		return math.Inf(1)
+	}
+	return w.End
+}
+
+type Config struct {
+	// Seed drives the local search, the same problem and seed always produce the same plan
+	Seed uint64
+	// Iterations is the number of local search moves tried, defaults to 2000
+	Iterations int
+}
+
+type Plan struct {
+	// Routes holds the routes of the vehicles that visit at least one stop, by vehicle index
+	Routes []Route
+	// Unassigned lists the stops no vehicle can visit within the constraints
+	Unassigned []int
+	Distance   float64
+	Duration   float64
+}
+
+type Route struct {
+	Vehicle int
+	Visits  []Visit
+	// Departure is when the vehicle leaves its start depot, as late as possible without waiting at the first stop
+	Departure float64
+	// Return is when the vehicle reaches its end depot
+	Return   float64
+	Distance float64
+	// Duration is the time from Departure to Return, including service and waiting times
+	Duration float64
+	Load     float64
+}
+
+type Visit struct {
+	Stop    int
+	Arrival float64
+	// Start is when the service begins, after waiting for the time window to open
+	Start     float64
+	Departure float64
+}
+
+// Solve plans the routes of the vehicles, cfg may be nil
+func Solve(problem *Problem, cfg *Config) (*Plan, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := problem.validate(); err != nil {
+		return nil, err
+	}
+
+	iterations := cfg.Iterations
+	if iterations <= 0 {
+		iterations = defaultIterations
+	}
+
+	s := &solver{
+		problem: problem,
+		rng:     rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		routes:  make([][]int, len(problem.Vehicles)),
+		costs:   make([]float64, len(problem.Vehicles)),
+	}
+	s.construct()
+	for range iterations {
+		s.improve()
+	}
+
+	return s.plan(), nil
+}
+
+func (p *Problem) validate() error {
+	locations := len(p.Durations)
+	if len(p.Distances) != locations {
+		return fmt.Errorf("%w: durations and distances have different sizes", ErrInvalidProblem)
+	}
+	for i := range locations {
+		if len(p.Durations[i]) != locations || len(p.Distances[i]) != locations {
+			return fmt.Errorf("%w: matrices must be square", ErrInvalidProblem)
+		}
+	}
+
+	inRange := func(location int) bool { return location >= 0 && location < locations }
+	for i, v := range p.Vehicles {
+		if !inRange(v.Start) || !inRange(v.End) {
+			return fmt.Errorf("%w: vehicle %d has an unknown depot", ErrInvalidProblem, i)
+		}
+		if v.Capacity < 0 || v.Shift.Start < 0 || v.Shift.end() < v.Shift.Start {
+			return fmt.Errorf("%w: vehicle %d has an invalid capacity or shift", ErrInvalidProblem, i)
+		}
+	}
+	for i, stop := range p.Stops {
+		if !inRange(stop.Location) {
+			return fmt.Errorf("%w: stop %d has an unknown location", ErrInvalidProblem, i)
+		}
+		if stop.Demand < 0 || stop.ServiceTime < 0 || stop.Window.Start < 0 || stop.Window.end() < stop.Window.Start {
+			return fmt.Errorf("%w: stop %d has an invalid demand, service time or time window", ErrInvalidProblem, i)
+		}
+	}
+
+	return nil
+}
+
+type solver struct {
+	problem *Problem
+	rng     *rand.Rand
+	// routes holds the stops of every vehicle in visiting order
+	routes [][]int
+	// costs holds the duration of every route, see cost
+	costs      []float64
+	unassigned []int
+	// scratch avoids allocating a route for every candidate insertion
+	scratch []int
+}
+
+// construct inserts the stops with the earliest deadlines first, each at its cheapest feasible position
+func (s *solver) construct() {
+	order := make([]int, len(s.problem.Stops))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(s.problem.Stops[a].Window.end(), s.problem.Stops[b].Window.end())
+	})
+
+	for _, stop := range order {
+		if !s.insert(stop) {
+			s.unassigned = append(s.unassigned, stop)
+		}
+	}
+}
+
+// improve tries one random move and keeps it if it shortens the plan
+func (s *solver) improve() {
+	used := make([]int, 0, len(s.routes))
+	for v, route := range s.routes {
+		if len(route) > 0 {
+			used = append(used, v)
+		}
+	}
+	if len(used) == 0 {
+		return
+	}
+
+	v := used[s.rng.IntN(len(used))]
+	improved := false
+	if s.rng.IntN(2) == 0 {
+		improved = s.relocate(v, s.rng.IntN(len(s.routes[v])))
+	} else if n := len(s.routes[v]); n >= 2 {
+		i := s.rng.IntN(n - 1)
+		improved = s.reverse(v, i, i+1+s.rng.IntN(n-1-i))
+	}
+
+	// A shorter plan may leave room for stops that didn't fit before
+	if improved && len(s.unassigned) > 0 {
+		s.unassigned = slices.DeleteFunc(s.unassigned, s.insert)
+	}
+}
+
+// relocate moves a stop to its cheapest position, in any route
+func (s *solver) relocate(v, i int) bool {
+	route := s.routes[v]
+	stop := route[i]
+	oldCost := s.costs[v]
+
+	removed := slices.Delete(slices.Clone(route), i, i+1)
+	// Durations don't always respect the triangle inequality, so removing a stop can delay the next ones
+	removedCost, ok := s.cost(v, removed)
+	if !ok {
+		return false
+	}
+	s.routes[v], s.costs[v] = removed, removedCost
+
+	bestV, bestPos, delta, _ := s.bestInsertion(stop)
+	if bestV == v && bestPos == i || removedCost+delta >= oldCost-epsilon {
+		s.routes[v], s.costs[v] = route, oldCost
+		return false
+	}
+
+	s.apply(stop, bestV, bestPos)
+	return true
+}
+
+// reverse reverses the stops i to j of a route, the 2-opt move
+func (s *solver) reverse(v, i, j int) bool {
+	candidate := slices.Clone(s.routes[v])
+	slices.Reverse(candidate[i : j+1])
+
+	cost, ok := s.cost(v, candidate)
+	if !ok || cost >= s.costs[v]-epsilon {
+		return false
+	}
+
+	s.routes[v], s.costs[v] = candidate, cost
+	return true
+}
+
+// insert adds the stop at its cheapest feasible position, it returns false if there is none
+func (s *solver) insert(stop int) bool {
+	v, pos, _, ok := s.bestInsertion(stop)
+	if !ok {
+		return false
+	}
+	s.apply(stop, v, pos)
+	return true
+}
+
+func (s *solver) apply(stop, v, pos int) {
+	s.routes[v] = slices.Insert(slices.Clone(s.routes[v]), pos, stop)
+	s.costs[v], _ = s.cost(v, s.routes[v])
+}
+
+// bestInsertion returns the vehicle and position where inserting the stop adds the least duration
+func (s *solver) bestInsertion(stop int) (vehicle, pos int, delta float64, ok bool) {
+	delta = math.Inf(1)
+	for v, route := range s.routes {
+		for p := 0; p <= len(route); p++ {
+			s.scratch = append(append(append(s.scratch[:0], route[:p]...), stop), route[p:]...)
+			cost, feasible := s.cost(v, s.scratch)
+			if feasible && cost-s.costs[v] < delta {
+				vehicle, pos, delta, ok = v, p, cost-s.costs[v], true
+			}
+		}
+	}
+	return vehicle, pos, delta, ok
+}
+
+// cost returns the duration of the route, it returns false if the route breaks a constraint
+func (s *solver) cost(v int, stops []int) (float64, bool) {
+	if len(stops) == 0 {
+		return 0, true
+	}
+	route, ok := s.schedule(v, stops, nil)
+	return route.Duration, ok
+}
+
+// schedule simulates the route, filling visits if not nil. It returns false if the route breaks a constraint.
+func (s *solver) schedule(v int, stops []int, visits []Visit) (Route, bool) {
+	vehicle := s.problem.Vehicles[v]
+	route := Route{Vehicle: v, Visits: visits}
+
+	t, location := vehicle.Shift.Start, vehicle.Start
+	for i, index := range stops {
+		stop := s.problem.Stops[index]
+		travel := s.problem.Durations[location][stop.Location]
+		if math.IsInf(travel, 0) || math.IsNaN(travel) {
+			return route, false
+		}
+
+		arrival := t + travel
+		start := max(arrival, stop.Window.Start)
+		if start > stop.Window.end() {
+			return route, false
+		}
+		if i == 0 {
+			// Leave late enough not to wait at the first stop
+			route.Departure = max(vehicle.Shift.Start, start-travel)
+			arrival = route.Departure + travel
+		}
+		t = start + stop.ServiceTime
+
+		route.Load += stop.Demand
+		route.Distance += s.problem.Distances[location][stop.Location]
+		if visits != nil {
+			route.Visits[i] = Visit{Stop: index, Arrival: arrival, Start: start, Departure: t}
+		}
+		location = stop.Location
+	}
+
+	travel := s.problem.Durations[location][vehicle.End]
+	if math.IsInf(travel, 0) || math.IsNaN(travel) {
+		return route, false
+	}
+	route.Return = t + travel
+	route.Distance += s.problem.Distances[location][vehicle.End]
+	route.Duration = route.Return - route.Departure
+
+	if vehicle.Capacity > 0 && route.Load > vehicle.Capacity+epsilon {
+		return route, false
+	}
+	return route, route.Return <= vehicle.Shift.end()
+}
+
+func (s *solver) plan() *Plan {
+	plan := &Plan{
+		Routes:     make([]Route, 0),
+		Unassigned: slices.Sorted(slices.Values(s.unassigned)),
+	}
+	for v, stops := range s.routes {
+		if len(stops) == 0 {
+			continue
+		}
+		route, _ := s.schedule(v, stops, make([]Visit, len(stops)))
+		plan.Routes = append(plan.Routes, route)
+		plan.Distance += route.Distance
+		plan.Duration += route.Duration
+	}
+	if plan.Unassigned == nil {
+		plan.Unassigned = make([]int, 0)
+	}
+	return plan
+}
@@ -0,0 +1,132 @@
+package planner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineProblem places the depot at location 0 and the stops at locations 1 to n, location i being i kilometers
+// away from the depot on a straight road driven at 10 meters per second
+func lineProblem(stops int, vehicles ...Vehicle) *Problem {
+	locations := stops + 1
+	problem := &Problem{
+		Durations: make([][]float64, locations),
+		Distances: make([][]float64, locations),
+		Vehicles:  vehicles,
+		Stops:     make([]Stop, stops),
+	}
+	for i := range locations {
+		problem.Durations[i] = make([]float64, locations)
+		problem.Distances[i] = make([]float64, locations)
+		for j := range locations {
+			problem.Distances[i][j] = math.Abs(float64(i-j)) * 1000
+			problem.Durations[i][j] = problem.Distances[i][j] / 10
+		}
+	}
+	for i := range problem.Stops {
+		problem.Stops[i] = Stop{Location: i + 1, Demand: 1}
+	}
+	return problem
+}
+
+func visitedStops(route Route) []int {
+	stops := make([]int, len(route.Visits))
+	for i, visit := range route.Visits {
+		stops[i] = visit.Stop
+	}
+	return stops
+}
+
+func TestSolve_SingleVehicle(t *testing.T) {
+	problem := lineProblem(4, Vehicle{})
+	problem.Stops[0].Location, problem.Stops[2].Location = 3, 1
+
+	plan, err := Solve(problem, nil)
+
+	require.NoError(t, err)
+	require.Len(t, plan.Routes, 1)
+	assert.Empty(t, plan.Unassigned)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, visitedStops(plan.Routes[0]))
+	// Driving out to the last stop and back is optimal
+	assert.Equal(t, 8000.0, plan.Distance)
+	assert.Equal(t, 800.0, plan.Duration)
+}
+
+func TestSolve_Capacity(t *testing.T) {
+	problem := lineProblem(6, Vehicle{Capacity: 3}, Vehicle{Capacity: 3})
+
+	plan, err := Solve(problem, nil)
+
+	require.NoError(t, err)
+	require.Len(t, plan.Routes, 2)
+	assert.Empty(t, plan.Unassigned)
+	for _, route := range plan.Routes {
+		assert.LessOrEqual(t, route.Load, 3.0)
+	}
+}
+
+func TestSolve_TimeWindows(t *testing.T) {
+	problem := lineProblem(3, Vehicle{Shift: Window{Start: 900, End: 5000}})
+	// The windows force the far stop first, then the stops on the way back
+	problem.Stops[2].Window = Window{Start: 1250, End: 1300}
+	problem.Stops[1].Window = Window{Start: 1350}
+	problem.Stops[0].Window = Window{Start: 2000}
+	problem.Stops[0].ServiceTime = 60
+
+	plan, err := Solve(problem, nil)
+
+	require.NoError(t, err)
+	require.Len(t, plan.Routes, 1)
+	route := plan.Routes[0]
+	assert.Equal(t, []int{2, 1, 0}, visitedStops(route))
+	assert.Equal(t, 950.0, route.Departure)
+	assert.Equal(t, Visit{Stop: 2, Arrival: 1250, Start: 1250, Departure: 1250}, route.Visits[0])
+	// The vehicle waits at the last stop for its window to open
+	assert.Equal(t, Visit{Stop: 0, Arrival: 1450, Start: 2000, Departure: 2060}, route.Visits[2])
+	assert.Equal(t, 2160.0, route.Return)
+	assert.Equal(t, 1210.0, route.Duration)
+}
+
+func TestSolve_Unassigned(t *testing.T) {
+	problem := lineProblem(3, Vehicle{Shift: Window{End: 500}})
+	problem.Durations[0][2] = math.Inf(1)
+	problem.Durations[1][2] = math.Inf(1)
+	problem.Durations[3][2] = math.Inf(1)
+
+	plan, err := Solve(problem, nil)
+
+	require.NoError(t, err)
+	// Stop 1 is unreachable, stop 2 is too far for the shift
+	assert.Equal(t, []int{1, 2}, plan.Unassigned)
+	require.Len(t, plan.Routes, 1)
+	assert.Equal(t, []int{0}, visitedStops(plan.Routes[0]))
+}
+
+func TestSolve_Deterministic(t *testing.T) {
+	newProblem := func() *Problem {
+		problem := lineProblem(30, Vehicle{Capacity: 8}, Vehicle{Capacity: 8}, Vehicle{Capacity: 8}, Vehicle{Capacity: 8})
+		for i := range problem.Stops {
+			problem.Stops[i].Location = (i*7)%30 + 1
+			problem.Stops[i].Demand = float64(i%3 + 1)
+		}
+		return problem
+	}
+
+	first, err := Solve(newProblem(), &Config{Seed: 42})
+	require.NoError(t, err)
+	second, err := Solve(newProblem(), &Config{Seed: 42})
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+}
+
+func TestSolve_InvalidProblem(t *testing.T) {
+	problem := lineProblem(2, Vehicle{Start: 5})
+
+	_, err := Solve(problem, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidProblem)
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Exporter selects where finished spans are sent
+type Exporter string
+
+const (
+	// ExporterNone keeps propagating trace context but doesn't export spans
+	ExporterNone Exporter = "none"
+	// ExporterStdout writes spans to stdout, handy to test locally without a collector
+	ExporterStdout Exporter = "stdout"
+	// ExporterOTLP sends spans to an OTLP/HTTP collector, configured with the standard OTEL_EXPORTER_OTLP_* variables
+	ExporterOTLP Exporter = "otlp"
+)
+
+const (
+	defaultServiceName = "delivery-route-system"
+	defaultSampleRatio = 1.0
+)
+
+type Config struct {
+	Exporter    Exporter
+	ServiceName string
+	// SampleRatio is the fraction of new traces that are sampled, traces started upstream follow the caller's decision
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace-context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter := ExporterNone
+	if cfg.Exporter != "" {
+		exporter = cfg.Exporter
+	}
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporter, err)
+	}
+
+	serviceName := defaultServiceName
+	if cfg.ServiceName != "" {
+		serviceName = cfg.ServiceName
+	}
+
+	sampleRatio := defaultSampleRatio
+	if cfg.SampleRatio > 0 {
+		sampleRatio = cfg.SampleRatio
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over the configured service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
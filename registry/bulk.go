@@ -0,0 +1,359 @@
+package registry
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+// ErrInvalidImport is returned when an import file can't be read at all, invalid rows are reported in ImportResult
+var ErrInvalidImport = errors.New("invalid import file")
+
+// Format is a file format pickup points are imported from and exported to
+type Format string
+
+const (
+	// FormatCSV has a header row naming the columns, see csvColumns
+	FormatCSV Format = "csv"
+	// FormatGeoJSON is a FeatureCollection of Point features, the other fields being properties
+	FormatGeoJSON Format = "geojson"
+)
+
+func (f Format) Validate() error {
+	switch f {
+	case FormatCSV, FormatGeoJSON:
+		return nil
+	default:
+		return fmt.Errorf("format must be one of %s, %s", FormatCSV, FormatGeoJSON)
+	}
+}
+
+// csvColumns are the columns of exported CSV files. Imports need external_id, name, latitude and longitude
+// in any order, the id column and unknown columns are ignored.
+var csvColumns = []string{"id", "external_id", "name", "latitude", "longitude", "carrier", "opening_hours", "capacity"}
+
+type ImportOptions struct {
+	// DryRun validates the rows and counts the changes without writing to the store
+	DryRun bool
+}
+
+type ImportResult struct {
+	// Created and Updated count the pickup points the import creates and updates, matched by external ID.
+	// They are not written on a dry run or if any row is invalid.
+	Created int
+	Updated int
+	Errors  []RowError
+}
+
+type RowError struct {
+	// Row is the line of a CSV record, the header being line 1, or the position of a GeoJSON feature starting at 1
+	Row        int
+	ExternalID string
+	Message    string
+}
+
+// row is a pickup point read from an import file
+type row struct {
+	number int
+	point  *PickupPoint
+	err    error
+}
+
+// Import creates or updates the pickup points read from r, matching them to stored ones by external ID.
+// Nothing is written if any row is invalid or the store fails to write them.
+// opts may be nil.
+func Import(ctx context.Context, store Store, r io.Reader, format Format, opts *ImportOptions) (*ImportResult, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	var rows []row
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, err = decodeCSV(r)
+	case FormatGeoJSON:
+		rows, err = decodeGeoJSON(r)
+	default:
+		err = format.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*PickupPoint, len(stored))
+	for _, p := range stored {
+		if _, ok := existing[p.ExternalID]; p.ExternalID != "" && !ok {
+			existing[p.ExternalID] = p
+		}
+	}
+
+	result := &ImportResult{Errors: make([]RowError, 0)}
+	// seen holds the row every external ID was first read on
+	seen := make(map[string]int, len(rows))
+	for i, row := range rows {
+		if row.err == nil {
+			if first, ok := seen[row.point.ExternalID]; ok {
+				rows[i].err = fmt.Errorf("external id is already on row %d", first)
+			} else {
+				seen[row.point.ExternalID] = row.number
+			}
+		}
+		if rows[i].err != nil {
+			result.Errors = append(result.Errors, RowError{Row: row.number, ExternalID: row.point.ExternalID, Message: rows[i].err.Error()})
+			continue
+		}
+
+		if _, ok := existing[row.point.ExternalID]; ok {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+	}
+
+	if opts.DryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	points := make([]*PickupPoint, len(rows))
+	for i, row := range rows {
+		points[i] = row.point
+		if match, ok := existing[row.point.ExternalID]; ok {
+			points[i].ID = match.ID
+		}
+	}
+	if err := store.Upsert(ctx, points); err != nil {
+		return nil, fmt.Errorf("failed to import pickup points: %w", err)
+	}
+
+	return result, nil
+}
+
+func decodeCSV(r io.Reader) ([]row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read the CSV header: %s", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets often start UTF-8 files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	for _, name := range []string{"external_id", "name", "latitude", "longitude"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: the CSV header has no %s column", ErrInvalidImport, name)
+		}
+	}
+
+	rows := make([]row, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImport, err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		line, _ := reader.FieldPos(0)
+		p := &PickupPoint{
+			ExternalID:   field("external_id"),
+			Name:         field("name"),
+			Location:     service.Location(field("latitude") + "," + field("longitude")),
+			Carrier:      field("carrier"),
+			OpeningHours: field("opening_hours"),
+		}
+		imported := row{number: line, point: p}
+		if capacity := field("capacity"); capacity != "" {
+			if p.Capacity, err = strconv.Atoi(capacity); err != nil {
+				imported.err = errors.New("capacity must be a whole number")
+			}
+		}
+		if imported.err == nil {
+			imported.err = validateRow(p)
+		}
+		rows = append(rows, imported)
+	}
+}
+
+// featureCollection is the GeoJSON document of imports and exports
+type featureCollection struct {
+	Type     string            `json:"type"`
+	Features []json.RawMessage `json:"features"`
+}
+
+type feature struct {
+	Type string `json:"type"`
+	// ID is the ID in the registry, it is exported but ignored by imports
+	ID         string            `json:"id,omitempty"`
+	Geometry   *point            `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+type point struct {
+	Type string `json:"type"`
+	// Coordinates are longitude, latitude and optionally altitude, which is ignored
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type featureProperties struct {
+	ExternalID   string `json:"external_id"`
+	Name         string `json:"name"`
+	Carrier      string `json:"carrier,omitempty"`
+	OpeningHours string `json:"opening_hours,omitempty"`
+	Capacity     int    `json:"capacity,omitempty"`
+}
+
+func decodeGeoJSON(r io.Reader) ([]row, error) {
+	var collection featureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImport, err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("%w: GeoJSON must be a FeatureCollection", ErrInvalidImport)
+	}
+
+	rows := make([]row, len(collection.Features))
+	for i, raw := range collection.Features {
+		rows[i] = row{number: i + 1, point: &PickupPoint{}}
+
+		// Features are decoded one by one, so that a malformed feature is reported as an invalid row
+		var f feature
+		if err := json.Unmarshal(raw, &f); err != nil {
+			rows[i].err = fmt.Errorf("invalid feature: %s", err)
+			continue
+		}
+
+		p := rows[i].point
+		p.ExternalID = strings.TrimSpace(f.Properties.ExternalID)
+		p.Name = strings.TrimSpace(f.Properties.Name)
+		p.Carrier = f.Properties.Carrier
+		p.OpeningHours = f.Properties.OpeningHours
+		p.Capacity = f.Properties.Capacity
+
+		if f.Type != "Feature" || f.Geometry == nil || f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) < 2 {
+			rows[i].err = errors.New("feature must have a Point geometry")
+			continue
+		}
+		p.Location = service.Coordinate{Lat: f.Geometry.Coordinates[1], Lon: f.Geometry.Coordinates[0]}.Location()
+		rows[i].err = validateRow(p)
+	}
+	return rows, nil
+}
+
+// validateRow checks an imported pickup point and converts its location to the canonical format
+func validateRow(p *PickupPoint) error {
+	if p.ExternalID == "" {
+		return errors.New("external id is required")
+	}
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := p.Location.Validate(); err != nil {
+		return err
+	}
+	if p.Capacity < 0 {
+		return errors.New("capacity must not be negative")
+	}
+
+	c, _ := p.Location.Coordinate()
+	p.Location = c.Location()
+	return nil
+}
+
+// Export writes the pickup points in the format
+func Export(w io.Writer, points []*PickupPoint, format Format) error {
+	switch format {
+	case FormatCSV:
+		return exportCSV(w, points)
+	case FormatGeoJSON:
+		return exportGeoJSON(w, points)
+	default:
+		return format.Validate()
+	}
+}
+
+func exportCSV(w io.Writer, points []*PickupPoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, p := range points {
+		c, err := p.Location.Coordinate()
+		if err != nil {
+			return fmt.Errorf("pickup point %s has an invalid location: %w", p.ID, err)
+		}
+		capacity := ""
+		if p.Capacity != 0 {
+			capacity = strconv.Itoa(p.Capacity)
+		}
+
+		record := []string{
+			p.ID,
+			p.ExternalID,
+			p.Name,
+			strconv.FormatFloat(c.Lat, 'f', -1, 64),
+			strconv.FormatFloat(c.Lon, 'f', -1, 64),
+			p.Carrier,
+			p.OpeningHours,
+			capacity,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportGeoJSON(w io.Writer, points []*PickupPoint) error {
+	collection := featureCollection{Type: "FeatureCollection", Features: make([]json.RawMessage, len(points))}
+	for i, p := range points {
+		c, err := p.Location.Coordinate()
+		if err != nil {
+			return fmt.Errorf("pickup point %s has an invalid location: %w", p.ID, err)
+		}
+
+		collection.Features[i], err = json.Marshal(feature{
+			Type:     "Feature",
+			ID:       p.ID,
+			Geometry: &point{Type: "Point", Coordinates: []float64{c.Lon, c.Lat}},
+			Properties: featureProperties{
+				ExternalID:   p.ExternalID,
+				Name:         p.Name,
+				Carrier:      p.Carrier,
+				OpeningHours: p.OpeningHours,
+				Capacity:     p.Capacity,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importCSV = "\ufeffexternal_id,name,latitude,longitude,carrier,capacity\n" +
+	"ext-1,Kiosk Mitte,52.529407,13.397634,DHL,40\n" +
+	"ext-2,Locker, 52.523219 ,13.428555,,\n"
+
+const importGeoJSON = `{"type":"FeatureCollection","features":[
+	{"type":"Feature","geometry":{"type":"Point","coordinates":[13.397634,52.529407]},"properties":{"external_id":"ext-1","name":"Kiosk Mitte","carrier":"DHL","capacity":40}},
+	{"type":"Feature","geometry":{"type":"Point","coordinates":[13.428555,52.523219,34]},"properties":{"external_id":"ext-2","name":"Locker"}}
+]}`
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		format Format
+		file   string
+	}{
+		{format: FormatCSV, file: importCSV},
+		{format: FormatGeoJSON, file: importGeoJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			require.NoError(t, store.Create(ctx, &PickupPoint{ID: "kiosk", ExternalID: "ext-1", Name: "Kiosk", Location: "52.5,13.4"}))
+
+			result, err := Import(ctx, store, strings.NewReader(tt.file), tt.format, &ImportOptions{DryRun: true})
+			require.NoError(t, err)
+			assert.Equal(t, &ImportResult{Created: 1, Updated: 1, Errors: []RowError{}}, result)
+			points, err := store.List(ctx)
+			require.NoError(t, err)
+			assert.Len(t, points, 1, "a dry run must not write")
+
+			result, err = Import(ctx, store, strings.NewReader(tt.file), tt.format, nil)
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.Created)
+			assert.Equal(t, 1, result.Updated)
+
+			updated, err := store.Get(ctx, "kiosk")
+			require.NoError(t, err)
+			assert.Equal(t, "Kiosk Mitte", updated.Name)
+			assert.Equal(t, "52.529407,13.397634", updated.Location.String())
+			assert.Equal(t, 40, updated.Capacity)
+
+			points, err = store.List(ctx)
+			require.NoError(t, err)
+			require.Len(t, points, 2)
+		})
+	}
+}
+
+func TestImport_InvalidRows(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	file := "name,external_id,latitude,longitude,capacity\n" +
+		"Kiosk,ext-1,52.529407,13.397634,\n" +
+		",ext-2,52.523219,13.428555,\n" +
+		"Locker,ext-3,95,13.428555,\n" +
+		"Locker,ext-1,52.523219,13.428555,\n" +
+		"Locker,ext-4,52.523219,13.428555,many\n" +
+		"Locker,,52.523219,13.428555\n"
+
+	result, err := Import(ctx, store, strings.NewReader(file), FormatCSV, nil)
+
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 5)
+	assert.Equal(t, RowError{Row: 3, ExternalID: "ext-2", Message: "name is required"}, result.Errors[0])
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Contains(t, result.Errors[1].Message, "latitude")
+	assert.Equal(t, RowError{Row: 5, ExternalID: "ext-1", Message: "external id is already on row 2"}, result.Errors[2])
+	assert.Equal(t, RowError{Row: 6, ExternalID: "ext-4", Message: "capacity must be a whole number"}, result.Errors[3])
+	assert.Equal(t, RowError{Row: 7, Message: "external id is required"}, result.Errors[4])
+	points, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, points, "nothing must be written if a row is invalid")
+}
+
+func TestImport_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		file   string
+	}{
+		{name: "missing CSV column", format: FormatCSV, file: "external_id,name,latitude\next-1,Kiosk,52.5\n"},
+		{name: "malformed JSON", format: FormatGeoJSON, file: `{"type":`},
+		{name: "not a FeatureCollection", format: FormatGeoJSON, file: `{"type":"Feature"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Import(context.Background(), NewMemoryStore(), strings.NewReader(tt.file), tt.format, nil)
+
+			assert.ErrorIs(t, err, ErrInvalidImport)
+		})
+	}
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatGeoJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			ctx := context.Background()
+			source := NewMemoryStore()
+			require.NoError(t, source.Create(ctx, &PickupPoint{ExternalID: "ext-1", Name: "Kiosk, Mitte", Location: "52.529407,13.397634", Carrier: "DHL", OpeningHours: "Mo-Sa 08:00-20:00", Capacity: 40}))
+			require.NoError(t, source.Create(ctx, &PickupPoint{ExternalID: "ext-2", Name: "Locker", Location: "-33.8688,151.2093"}))
+			exported, err := source.List(ctx)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, Export(&buf, exported, format))
+
+			target := NewMemoryStore()
+			result, err := Import(ctx, target, &buf, format, nil)
+			require.NoError(t, err)
+			assert.Equal(t, 2, result.Created)
+
+			imported, err := target.List(ctx)
+			require.NoError(t, err)
+			require.Len(t, imported, 2)
+			byExternalID := make(map[string]*PickupPoint)
+			for _, p := range imported {
+				byExternalID[p.ExternalID] = p
+			}
+			for _, p := range exported {
+				got := byExternalID[p.ExternalID]
+				require.NotNil(t, got)
+				assert.Equal(t, p.Name, got.Name)
+				assert.Equal(t, p.Location, got.Location)
+				assert.Equal(t, p.Carrier, got.Carrier)
+				assert.Equal(t, p.OpeningHours, got.OpeningHours)
+				assert.Equal(t, p.Capacity, got.Capacity)
+			}
+		})
+	}
+}
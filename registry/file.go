@@ -0,0 +1,181 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked is returned when another FileStore, e.g. a running server, has the pickup point file open
+var ErrLocked = errors.New("pickup point file is in use by another process")
+
+// FileStore keeps the pickup points in memory and writes all of them to a JSON file on every change.
+// It suits registries of up to a few thousand pickup points written by a single instance: the file is locked
+// until Close, so that a second instance can't overwrite the changes of the first with its stale copy.
+type FileStore struct {
+	path string
+	// lock is the open <path>.lock file holding the exclusive lock
+	lock *os.File
+	// memory holds the pickup points, its mutex also serializes the writes to the file
+	memory *MemoryStore
+}
+
+type FileStoreConfig struct {
+	// Path is the JSON file, it is created on the first change if it doesn't exist.
+	// It is locked through <Path>.lock, which is left in place.
+	Path string
+}
+
+func NewFileStore(cfg *FileStoreConfig) (*FileStore, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, errors.New("pickup point file path must be specified")
+	}
+
+	lock, err := os.OpenFile(cfg.Path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pickup point lock file: %w", err)
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, cfg.Path)
+		}
+		return nil, fmt.Errorf("failed to lock pickup points: %w", err)
+	}
+
+	s := &FileStore{
+		path:   cfg.Path,
+		lock:   lock,
+		memory: NewMemoryStore(),
+	}
+	if err := s.load(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pickup points: %w", err)
+	}
+
+	var points []*PickupPoint
+	if err := json.Unmarshal(data, &points); err != nil {
+		return fmt.Errorf("failed to decode pickup points from %s: %w", s.path, err)
+	}
+	for _, p := range points {
+		s.memory.points[p.ID] = p
+	}
+	return nil
+}
+
+// Close releases the lock on the file, the store must not be used afterwards
+func (s *FileStore) Close() error {
+	return s.lock.Close()
+}
+
+func (s *FileStore) Create(ctx context.Context, p *PickupPoint) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	if err := s.memory.create(p); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		delete(s.memory.points, p.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Get(ctx context.Context, id string) (*PickupPoint, error) {
+	return s.memory.Get(ctx, id)
+}
+
+func (s *FileStore) List(ctx context.Context) ([]*PickupPoint, error) {
+	return s.memory.List(ctx)
+}
+
+func (s *FileStore) Update(ctx context.Context, p *PickupPoint) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	previous := s.memory.points[p.ID]
+	if err := s.memory.update(p); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.memory.points[p.ID] = previous
+		return err
+	}
+	return nil
+}
+
+// Upsert writes the file once for all the pickup points
+func (s *FileStore) Upsert(ctx context.Context, points []*PickupPoint) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	previous := s.memory.upsert(points)
+	if err := s.save(); err != nil {
+		for id, p := range previous {
+			if p == nil {
+				delete(s.memory.points, id)
+			} else {
+				s.memory.points[id] = p
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	previous := s.memory.points[id]
+	if err := s.memory.delete(id); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.memory.points[id] = previous
+		return err
+	}
+	return nil
+}
+
+// save writes the pickup points to a temporary file renamed over the previous one, so a crash never leaves
+// a partially written file. The caller must hold the memory store's lock.
+func (s *FileStore) save() error {
+	data, err := json.MarshalIndent(s.memory.list(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pickup points: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save pickup points: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save pickup points: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save pickup points: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save pickup points: %w", err)
+	}
+	return nil
+}
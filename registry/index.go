@@ -0,0 +1,246 @@
+package registry
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+// Neighbor is a pickup point found by a nearest search
+type Neighbor struct {
+	PickupPoint *PickupPoint
+	// Distance is the great-circle distance in meters from the searched location
+	Distance float64
+}
+
+// Index is an immutable k-d tree over pickup points. Points are indexed as unit vectors in 3D,
+// so that straight-line (chord) distances order them like great-circle distances, without
+// special cases at the poles or the antimeridian.
+type Index struct {
+	// nodes is the tree stored in place: the root of every range is the node in its middle
+	nodes []indexNode
+}
+
+type indexNode struct {
+	point  *PickupPoint
+	vector [3]float64
+}
+
+// NewIndex indexes the pickup points, those with an invalid location are skipped
+func NewIndex(points []*PickupPoint) *Index {
+	nodes := make([]indexNode, 0, len(points))
+	for _, p := range points {
+		c, err := p.Location.Coordinate()
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, indexNode{point: p, vector: unitVector(c)})
+	}
+
+	build(nodes, 0)
+	return &Index{nodes: nodes}
+}
+
+func build(nodes []indexNode, axis int) {
+	if len(nodes) <= 1 {
+		return
+	}
+	slices.SortFunc(nodes, func(a, b indexNode) int {
+		switch {
+		case a.vector[axis] < b.vector[axis]:
+			return -1
+		case a.vector[axis] > b.vector[axis]:
+			return 1
+		default:
+			return 0
+		}
+	})
+	mid := len(nodes) / 2
+	build(nodes[:mid], (axis+1)%3)
+	build(nodes[mid+1:], (axis+1)%3)
+}
+
+func (idx *Index) Len() int {
+	return len(idx.nodes)
+}
+
+// Nearest returns up to k pickup points closest to the location, nearest first.
+// Points further than radius meters are ignored, radius 0 means no limit.
+func (idx *Index) Nearest(location service.Coordinate, k int, radius float64) []Neighbor {
+	if k <= 0 || len(idx.nodes) == 0 {
+		return []Neighbor{}
+	}
+
+	maxChord := math.Inf(1)
+	if radius > 0 && radius < math.Pi*service.EarthRadius {
+		maxChord = chordLength(radius)
+	}
+
+	s := &search{target: unitVector(location), k: k, maxChord2: maxChord * maxChord}
+	s.visit(idx.nodes, 0)
+
+	neighbors := make([]Neighbor, s.found.Len())
+	for i := len(neighbors) - 1; i >= 0; i-- {
+		c := heap.Pop(&s.found).(candidate)
+		neighbors[i] = Neighbor{PickupPoint: c.point, Distance: arcLength(math.Sqrt(c.chord2))}
+	}
+	return neighbors
+}
+
+type search struct {
+	target    [3]float64
+	k         int
+	maxChord2 float64
+	found     candidates
+}
+
+func (s *search) visit(nodes []indexNode, axis int) {
+	if len(nodes) == 0 {
+		return
+	}
+	mid := len(nodes) / 2
+	node := nodes[mid]
+
+	if d2 := squaredDistance(node.vector, s.target); d2 <= s.maxChord2 {
+		if s.found.Len() < s.k {
+			heap.Push(&s.found, candidate{point: node.point, chord2: d2})
+		} else if d2 < s.found[0].chord2 {
+			s.found[0] = candidate{point: node.point, chord2: d2}
+			heap.Fix(&s.found, 0)
+		}
+	}
+
+	diff := s.target[axis] - node.vector[axis]
+	near, far := nodes[:mid], nodes[mid+1:]
+	if diff > 0 {
+		near, far = far, near
+	}
+	s.visit(near, (axis+1)%3)
+	if diff*diff <= s.bound() {
+		s.visit(far, (axis+1)%3)
+	}
+}
+
+// bound is the squared chord length beyond which no point can be accepted
+func (s *search) bound() float64 {
+	if s.found.Len() < s.k {
+		return s.maxChord2
+	}
+	return min(s.found[0].chord2, s.maxChord2)
+}
+
+type candidate struct {
+	point  *PickupPoint
+	chord2 float64
+}
+
+// candidates is a max-heap on the distance, so the furthest candidate is replaced first
+type candidates []candidate
+
+func (c candidates) Len() int           { return len(c) }
+func (c candidates) Less(i, j int) bool { return c[i].chord2 > c[j].chord2 }
+func (c candidates) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c *candidates) Push(x any)        { *c = append(*c, x.(candidate)) }
+func (c *candidates) Pop() any {
+	old := *c
+	last := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return last
+}
+
+func unitVector(c service.Coordinate) [3]float64 {
+	lat, lon := c.Lat*math.Pi/180, c.Lon*math.Pi/180
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func squaredDistance(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// chordLength converts a great-circle distance in meters to the chord between unit vectors
+func chordLength(arc float64) float64 {
+	return 2 * math.Sin(arc/service.EarthRadius/2)
+}
+
+// arcLength converts the chord between unit vectors to a great-circle distance in meters
+func arcLength(chord float64) float64 {
+	return 2 * service.EarthRadius * math.Asin(min(chord/2, 1))
+}
+
+// IndexedStore adds nearest searches to a Store. The index is rebuilt on the first search after a change.
+type IndexedStore struct {
+	Store
+
+	mu    sync.Mutex
+	index *Index
+	// version is incremented by every change, so that an index built during a change is not kept
+	version uint64
+}
+
+func NewIndexedStore(store Store) *IndexedStore {
+	return &IndexedStore{Store: store}
+}
+
+func (s *IndexedStore) Create(ctx context.Context, p *PickupPoint) error {
+	defer s.invalidate()
+	return s.Store.Create(ctx, p)
+}
+
+func (s *IndexedStore) Update(ctx context.Context, p *PickupPoint) error {
+	defer s.invalidate()
+	return s.Store.Update(ctx, p)
+}
+
+func (s *IndexedStore) Upsert(ctx context.Context, points []*PickupPoint) error {
+	defer s.invalidate()
+	return s.Store.Upsert(ctx, points)
+}
+
+func (s *IndexedStore) Delete(ctx context.Context, id string) error {
+	defer s.invalidate()
+	return s.Store.Delete(ctx, id)
+}
+
+func (s *IndexedStore) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index = nil
+	s.version++
+}
+
+// Nearest returns up to k pickup points closest to the location, nearest first.
+// Points further than radius meters are ignored, radius 0 means no limit.
+func (s *IndexedStore) Nearest(ctx context.Context, location service.Coordinate, k int, radius float64) ([]Neighbor, error) {
+	index, err := s.currentIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return index.Nearest(location, k, radius), nil
+}
+
+func (s *IndexedStore) currentIndex(ctx context.Context) (*Index, error) {
+	s.mu.Lock()
+	index, version := s.index, s.version
+	s.mu.Unlock()
+	if index != nil {
+		return index, nil
+	}
+
+	points, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index = NewIndex(points)
+
+	s.mu.Lock()
+	if s.version == version {
+		s.index = index
+	}
+	s.mu.Unlock()
+	return index, nil
+}
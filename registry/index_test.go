@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPoints(rng *rand.Rand, count int) []*PickupPoint {
+	points := make([]*PickupPoint, count)
+	for i := range points {
+		c := service.Coordinate{Lat: rng.Float64()*180 - 90, Lon: rng.Float64()*360 - 180}
+		points[i] = &PickupPoint{ID: fmt.Sprintf("p%d", i), Location: c.Location()}
+	}
+	return points
+}
+
+// nearestBruteForce sorts all the points by great-circle distance
+func nearestBruteForce(points []*PickupPoint, location service.Coordinate, k int, radius float64) []string {
+	type found struct {
+		id       string
+		distance float64
+	}
+	all := make([]found, 0)
+	for _, p := range points {
+		c, _ := p.Location.Coordinate()
+		if d := service.GreatCircleDistance(location, c); radius == 0 || d <= radius {
+			all = append(all, found{p.ID, d})
+		}
+	}
+	slices.SortFunc(all, func(a, b found) int {
+		if a.distance < b.distance {
+			return -1
+		}
+		return 1
+	})
+
+	ids := make([]string, 0, k)
+	for _, f := range all[:min(k, len(all))] {
+		ids = append(ids, f.id)
+	}
+	return ids
+}
+
+func TestIndex_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	points := randomPoints(rng, 2000)
+	index := NewIndex(points)
+
+	tests := []struct {
+		name   string
+		k      int
+		radius float64
+	}{
+		{name: "nearest 5", k: 5},
+		{name: "nearest 50 within 1000 km", k: 50, radius: 1_000_000},
+		{name: "more than there are within 100 km", k: 10, radius: 100_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				location := service.Coordinate{Lat: rng.Float64()*180 - 90, Lon: rng.Float64()*360 - 180}
+
+				neighbors := index.Nearest(location, tt.k, tt.radius)
+
+				ids := make([]string, len(neighbors))
+				for i, n := range neighbors {
+					ids[i] = n.PickupPoint.ID
+					c, _ := n.PickupPoint.Location.Coordinate()
+					assert.InDelta(t, service.GreatCircleDistance(location, c), n.Distance, 1)
+				}
+				assert.Equal(t, nearestBruteForce(points, location, tt.k, tt.radius), ids)
+			}
+		})
+	}
+}
+
+func TestIndex_Antimeridian(t *testing.T) {
+	index := NewIndex([]*PickupPoint{
+		{ID: "west", Location: "0,-179.9"},
+		{ID: "far", Location: "0,178"},
+	})
+
+	neighbors := index.Nearest(service.Coordinate{Lat: 0, Lon: 179.9}, 1, 0)
+
+	require.Len(t, neighbors, 1)
+	assert.Equal(t, "west", neighbors[0].PickupPoint.ID)
+	assert.InDelta(t, 22239, neighbors[0].Distance, 10)
+}
+
+func TestIndexedStore_RebuildsAfterChanges(t *testing.T) {
+	ctx := context.Background()
+	store := NewIndexedStore(NewMemoryStore())
+	require.NoError(t, store.Create(ctx, &PickupPoint{ID: "a", Location: "52.52,13.40"}))
+
+	neighbors, err := store.Nearest(ctx, service.Coordinate{Lat: 52.5, Lon: 13.4}, 5, 0)
+	require.NoError(t, err)
+	assert.Len(t, neighbors, 1)
+
+	require.NoError(t, store.Create(ctx, &PickupPoint{ID: "b", Location: "52.50,13.40"}))
+	neighbors, err = store.Nearest(ctx, service.Coordinate{Lat: 52.5, Lon: 13.4}, 5, 0)
+	require.NoError(t, err)
+	require.Len(t, neighbors, 2)
+	assert.Equal(t, "b", neighbors[0].PickupPoint.ID)
+
+	require.NoError(t, store.Upsert(ctx, []*PickupPoint{{ID: "c", Location: "52.51,13.40"}}))
+	neighbors, err = store.Nearest(ctx, service.Coordinate{Lat: 52.5, Lon: 13.4}, 5, 0)
+	require.NoError(t, err)
+	assert.Len(t, neighbors, 3)
+
+	require.NoError(t, store.Delete(ctx, "c"))
+	require.NoError(t, store.Delete(ctx, "b"))
+	neighbors, err = store.Nearest(ctx, service.Coordinate{Lat: 52.5, Lon: 13.4}, 5, 0)
+	require.NoError(t, err)
+	require.Len(t, neighbors, 1)
+	assert.Equal(t, "a", neighbors[0].PickupPoint.ID)
+}
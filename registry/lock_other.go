@@ -0,0 +1,10 @@
+//go:build !unix
+
+package registry
+
+import "os"
+
+// lockFile is a no-op where flock isn't available, a single instance must then be ensured otherwise
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,138 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the pickup points in memory, they are lost on restart
+type MemoryStore struct {
+	mu     sync.RWMutex
+	points map[string]*PickupPoint
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		points: make(map[string]*PickupPoint),
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, p *PickupPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(p)
+}
+
+func (s *MemoryStore) create(p *PickupPoint) error {
+	if p.ID == "" {
+		p.ID = NewID()
+	}
+	if _, ok := s.points[p.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, p.ID)
+	}
+
+	p.CreatedAt = s.now().UTC()
+	p.UpdatedAt = p.CreatedAt
+	stored := *p
+	s.points[p.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*PickupPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.points[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	found := *p
+	return &found, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]*PickupPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list(), nil
+}
+
+func (s *MemoryStore) list() []*PickupPoint {
+	points := make([]*PickupPoint, 0, len(s.points))
+	for _, p := range s.points {
+		listed := *p
+		points = append(points, &listed)
+	}
+	slices.SortFunc(points, func(a, b *PickupPoint) int { return strings.Compare(a.ID, b.ID) })
+	return points
+}
+
+func (s *MemoryStore) Update(ctx context.Context, p *PickupPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(p)
+}
+
+func (s *MemoryStore) update(p *PickupPoint) error {
+	existing, ok := s.points[p.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, p.ID)
+	}
+
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = s.now().UTC()
+	stored := *p
+	s.points[p.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) Upsert(ctx context.Context, points []*PickupPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.upsert(points)
+	return nil
+}
+
+// upsert returns the pickup points it replaced by ID, nil for the ones it created
+func (s *MemoryStore) upsert(points []*PickupPoint) map[string]*PickupPoint {
+	previous := make(map[string]*PickupPoint, len(points))
+	now := s.now().UTC()
+	for _, p := range points {
+		if p.ID == "" {
+			p.ID = NewID()
+		}
+		existing, ok := s.points[p.ID]
+		if _, replaced := previous[p.ID]; !replaced {
+			previous[p.ID] = existing
+		}
+
+		p.CreatedAt = now
+		if ok {
+			p.CreatedAt = existing.CreatedAt
+		}
+		p.UpdatedAt = now
+		stored := *p
+		s.points[p.ID] = &stored
+	}
+	return previous
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(id)
+}
+
+func (s *MemoryStore) delete(id string) error {
+	if _, ok := s.points[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(s.points, id)
+	return nil
+}
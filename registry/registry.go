@@ -0,0 +1,56 @@
+// Package registry stores the pickup points routes can be requested to by ID
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+var (
+	ErrNotFound      = errors.New("pickup point not found")
+	ErrAlreadyExists = errors.New("pickup point already exists")
+)
+
+type PickupPoint struct {
+	ID string `json:"id"`
+	// ExternalID is the ID in the system the pickup point is imported from, imports update the pickup point having it
+	ExternalID string `json:"external_id,omitempty"`
+	Name       string `json:"name"`
+	// Location is in the service's latitude,longitude order
+	Location service.Location `json:"location"`
+	Carrier  string           `json:"carrier,omitempty"`
+	// OpeningHours is free text, e.g. "Mo-Fr 08:00-20:00; Sa 09:00-14:00"
+	OpeningHours string `json:"opening_hours,omitempty"`
+	// Capacity is the number of parcels the pickup point can hold, 0 if unknown
+	Capacity  int       `json:"capacity,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store persists pickup points. Implementations are safe for concurrent use and return copies,
+// so callers may modify the pickup points they pass and get.
+type Store interface {
+	// Create adds the pickup point, generating its ID if empty, and sets its timestamps
+	Create(ctx context.Context, p *PickupPoint) error
+	Get(ctx context.Context, id string) (*PickupPoint, error)
+	// List returns all pickup points ordered by ID
+	List(ctx context.Context) ([]*PickupPoint, error)
+	// Update replaces the pickup point with the same ID, keeping its creation time
+	Update(ctx context.Context, p *PickupPoint) error
+	// Upsert updates the pickup points whose ID is stored and creates the others like Create.
+	// Either all of them are written or none.
+	Upsert(ctx context.Context, points []*PickupPoint) error
+	Delete(ctx context.Context, id string) error
+}
+
+// NewID returns a random 16 characters hexadecimal ID
+func NewID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"file": func(t *testing.T) Store {
+			s, err := NewFileStore(&FileStoreConfig{Path: filepath.Join(t.TempDir(), "pickup-points.json")})
+			require.NoError(t, err)
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			generated := &PickupPoint{Name: "Kiosk", Location: "52.517037,13.38886"}
+			require.NoError(t, s.Create(ctx, generated))
+			assert.Len(t, generated.ID, 16)
+			assert.False(t, generated.CreatedAt.IsZero())
+
+			require.NoError(t, s.Create(ctx, &PickupPoint{ID: "a", Name: "Locker", Location: "52.529407,13.397634", Capacity: 40}))
+			assert.ErrorIs(t, s.Create(ctx, &PickupPoint{ID: "a"}), ErrAlreadyExists)
+
+			got, err := s.Get(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, 40, got.Capacity)
+
+			got.Name = "Parcel locker"
+			require.NoError(t, s.Update(ctx, got))
+			updated, err := s.Get(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, "Parcel locker", updated.Name)
+			assert.Equal(t, got.CreatedAt, updated.CreatedAt)
+			assert.ErrorIs(t, s.Update(ctx, &PickupPoint{ID: "missing"}), ErrNotFound)
+
+			points, err := s.List(ctx)
+			require.NoError(t, err)
+			require.Len(t, points, 2)
+			assert.Less(t, points[0].ID, points[1].ID)
+
+			upserted := []*PickupPoint{{ID: "a", Name: "Locker", Location: "52.529407,13.397634"}, {Name: "Shop", Location: "52.523219,13.428555"}}
+			require.NoError(t, s.Upsert(ctx, upserted))
+			assert.Equal(t, got.CreatedAt, upserted[0].CreatedAt)
+			assert.Len(t, upserted[1].ID, 16)
+			created, err := s.Get(ctx, upserted[1].ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Shop", created.Name)
+			require.NoError(t, s.Delete(ctx, created.ID))
+
+			require.NoError(t, s.Delete(ctx, "a"))
+			_, err = s.Get(ctx, "a")
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.ErrorIs(t, s.Delete(ctx, "a"), ErrNotFound)
+		})
+	}
+}
+
+func TestFileStore_Reload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pickup-points.json")
+	s, err := NewFileStore(&FileStoreConfig{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, s.Create(ctx, &PickupPoint{ID: "a", Name: "Locker", Location: "52.529407,13.397634", Carrier: "DHL"}))
+	require.NoError(t, s.Create(ctx, &PickupPoint{ID: "b", Name: "Kiosk", Location: "52.517037,13.38886"}))
+	require.NoError(t, s.Delete(ctx, "b"))
+	require.NoError(t, s.Close())
+
+	reloaded, err := NewFileStore(&FileStoreConfig{Path: path})
+	require.NoError(t, err)
+	defer reloaded.Close()
+
+	points, err := reloaded.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "DHL", points[0].Carrier)
+}
+
+func TestFileStore_Locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pickup-points.json")
+	s, err := NewFileStore(&FileStoreConfig{Path: path})
+	require.NoError(t, err)
+
+	_, err = NewFileStore(&FileStoreConfig{Path: path})
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, s.Close())
+	reopened, err := NewFileStore(&FileStoreConfig{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, reopened.Close())
+}
+
+func TestFileStore_UpsertFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pickup-points.json")
+	s, err := NewFileStore(&FileStoreConfig{Path: path})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Create(ctx, &PickupPoint{ID: "a", Name: "Locker", Location: "52.529407,13.397634"}))
+
+	// The file can't be replaced by a directory
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "blocked"), 0o755))
+	err = s.Upsert(ctx, []*PickupPoint{{ID: "a", Name: "Parcel locker"}, {ID: "b", Name: "Kiosk"}})
+	require.Error(t, err)
+
+	points, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "Locker", points[0].Name)
+}
@@ -1,5 +1,10 @@
 package server
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Location represents a location in HTTP/transport layer (DTO)
 type Location string
 
@@ -10,15 +15,356 @@ func (l Location) String() string {
 type GetRoutesRequest struct {
 	Source       Location
 	Destinations []Location
+	// DestinationRefs are client IDs of Destinations, by position. They are echoed in the routes.
+	DestinationRefs []string
+	// DestinationIDs are IDs of registered pickup points, routed after Destinations
+	DestinationIDs  []string
+	MaxSnapDistance float64
+	SnapPolicy      string
+	CoordOrder      string
+	Profile         string
+	// Limit, MaxDuration and MaxDistance leave out routes after sorting, 0 means no limit
+	Limit       int
+	MaxDuration float64
+	MaxDistance float64
+	// Ranking is the strategy routes are sorted by, DurationWeight and DistanceWeight apply to weighted
+	// and Tolerance to tolerance
+	Ranking        string
+	DurationWeight float64
+	DistanceWeight float64
+	Tolerance      float64
 }
 
 type GetRoutesResponse struct {
 	Source Location `json:"source"`
-	Routes []*Route `json:"routes"`
+	// Ranking is the strategy the routes are sorted by, lowest score first
+	Ranking string   `json:"ranking"`
+	Routes  []*Route `json:"routes"`
+	// Filtered is set when destinations were left out of the routes
+	Filtered *FilteredRoutes `json:"filtered,omitempty"`
+}
+
+// FilteredRoutes counts the destinations left out of the routes, by reason
+type FilteredRoutes struct {
+	Total       int `json:"total"`
+	SnapTooFar  int `json:"snap_too_far,omitempty"`
+	Unreachable int `json:"unreachable,omitempty"`
+	MaxDuration int `json:"max_duration,omitempty"`
+	MaxDistance int `json:"max_distance,omitempty"`
+	Limit       int `json:"limit,omitempty"`
 }
 
 type Route struct {
 	Destination Location `json:"destination"`
+	// ID and Metadata are those the destination was sent with
+	ID       string          `json:"id,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// Index is the position of the destination in the request, counting pickup point IDs after locations
+	Index           int      `json:"index"`
+	Distance        float64  `json:"distance"`
+	Duration        float64  `json:"duration"`
+	SnappedLocation Location `json:"snapped_location,omitempty"`
+	SnapDistance    float64  `json:"snap_distance"`
+	SnapTooFar      bool     `json:"snap_too_far,omitempty"`
+	Unreachable     bool     `json:"unreachable,omitempty"`
+	// Estimated is set when the route was estimated from the great-circle distance while OSRM was unavailable
+	Estimated bool `json:"estimated,omitempty"`
+	// Score is the value of the route for the ranking strategy
+	Score float64 `json:"score"`
+	// PickupPoint is set when the destination was requested by pickup point ID
+	PickupPoint *PickupPoint `json:"pickup_point,omitempty"`
+}
+
+// MarshalJSON reports the duration, distance and score of unreachable routes as null rather than 0
+func (r Route) MarshalJSON() ([]byte, error) {
+	type route Route
+	if !r.Unreachable {
+		return json.Marshal(route(r))
+	}
+
+	return json.Marshal(struct {
+		route
+		Distance *float64 `json:"distance"`
+		Duration *float64 `json:"duration"`
+		Score    *float64 `json:"score"`
+	}{route: route(r)})
+}
+
+type GetRouteRequest struct {
+	Source      Location
+	Destination Location
+	CoordOrder  string
+	Profile     string
+	Geometry    string
+	Steps       bool
+}
+
+type GetRouteResponse struct {
+	Source      Location `json:"source"`
+	Destination Location `json:"destination"`
+	Distance    float64  `json:"distance"`
+	Duration    float64  `json:"duration"`
+	// Geometry is an encoded polyline string or a GeoJSON LineString
+	Geometry any         `json:"geometry"`
+	Steps    []RouteStep `json:"steps,omitempty"`
+}
+
+// LineString is a GeoJSON LineString, coordinates are [longitude, latitude] as required by GeoJSON
+type LineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+type RouteStep struct {
+	Instruction string   `json:"instruction"`
+	Name        string   `json:"name"`
 	Distance    float64  `json:"distance"`
 	Duration    float64  `json:"duration"`
+	Maneuver    string   `json:"maneuver"`
+	Modifier    string   `json:"modifier,omitempty"`
+	Location    Location `json:"location"`
+}
+
+// PostRoutesRequest is the JSON body accepted by POST /routes
+type PostRoutesRequest struct {
+	Source       Location           `json:"source"`
+	Destinations []RouteDestination `json:"destinations"`
+	// DestinationIDs are IDs of registered pickup points, routed after Destinations
+	DestinationIDs  []string `json:"destination_ids,omitempty"`
+	MaxSnapDistance float64  `json:"max_snap_distance,omitempty"`
+	SnapPolicy      string   `json:"snap_policy,omitempty"`
+	CoordOrder      string   `json:"coord_order,omitempty"`
+	Profile         string   `json:"profile,omitempty"`
+	Limit           int      `json:"limit,omitempty"`
+	MaxDuration     float64  `json:"max_duration,omitempty"`
+	MaxDistance     float64  `json:"max_distance,omitempty"`
+	Ranking         string   `json:"ranking,omitempty"`
+	DurationWeight  float64  `json:"duration_weight,omitempty"`
+	DistanceWeight  float64  `json:"distance_weight,omitempty"`
+	Tolerance       float64  `json:"tolerance,omitempty"`
+}
+
+// RouteDestination is a destination of POST /routes, sent either as a location string or as an object
+// with a client ID and metadata that are echoed in its route
+type RouteDestination struct {
+	Location Location `json:"location"`
+	ID       string   `json:"id,omitempty"`
+	// Metadata is an opaque JSON object
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// MarshalJSON writes destinations without ID and metadata as a location string
+func (d RouteDestination) MarshalJSON() ([]byte, error) {
+	type routeDestination RouteDestination
+	if d.ID == "" && len(d.Metadata) == 0 {
+		return json.Marshal(d.Location)
+	}
+	return json.Marshal(routeDestination(d))
+}
+
+func (d *RouteDestination) UnmarshalJSON(data []byte) error {
+	var location string
+	if err := json.Unmarshal(data, &location); err == nil {
+		*d = RouteDestination{Location: Location(location)}
+		return nil
+	}
+
+	type routeDestination RouteDestination
+	return json.Unmarshal(data, (*routeDestination)(d))
+}
+
+// PostMatrixRequest is the JSON body accepted by POST /matrix
+type PostMatrixRequest struct {
+	Sources      []Location `json:"sources"`
+	Destinations []Location `json:"destinations"`
+	CoordOrder   string     `json:"coord_order,omitempty"`
+	Profile      string     `json:"profile,omitempty"`
+}
+
+type PostMatrixResponse struct {
+	Sources      []Location `json:"sources"`
+	Destinations []Location `json:"destinations"`
+	// Cells[i][j] is the route from Sources[i] to Destinations[j]
+	Cells [][]MatrixCell `json:"cells"`
+}
+
+type MatrixCell struct {
+	Distance    float64 `json:"distance"`
+	Duration    float64 `json:"duration"`
+	Unreachable bool    `json:"unreachable,omitempty"`
+}
+
+// MarshalJSON reports the duration and distance of unreachable cells as null rather than 0
+func (c MatrixCell) MarshalJSON() ([]byte, error) {
+	type cell MatrixCell
+	if !c.Unreachable {
+		return json.Marshal(cell(c))
+	}
+
+	return json.Marshal(struct {
+		cell
+		Distance *float64 `json:"distance"`
+		Duration *float64 `json:"duration"`
+	}{cell: cell(c)})
+}
+
+// PostTripsRequest is the JSON body accepted by POST /trips
+type PostTripsRequest struct {
+	Source Location   `json:"source"`
+	Stops  []Location `json:"stops"`
+	// Roundtrip returns to the source after the last stop, defaults to true unless End is set
+	Roundtrip *bool `json:"roundtrip,omitempty"`
+	// End is a fixed location the trip ends at after visiting all stops
+	End        Location `json:"end,omitempty"`
+	CoordOrder string   `json:"coord_order,omitempty"`
+	Profile    string   `json:"profile,omitempty"`
+}
+
+type PostTripsResponse struct {
+	Source Location `json:"source"`
+	// Order lists the indices of the stops in visiting order
+	Order []int `json:"order"`
+	// Stops are the stops in visiting order
+	Stops    []Location `json:"stops"`
+	Legs     []TripLeg  `json:"legs"`
+	Distance float64    `json:"distance"`
+	Duration float64    `json:"duration"`
+}
+
+type TripLeg struct {
+	From     Location `json:"from"`
+	To       Location `json:"to"`
+	Distance float64  `json:"distance"`
+	Duration float64  `json:"duration"`
+}
+
+// PostPlansRequest is the JSON body accepted by POST /plans.
+// Times are in seconds from an epoch of the client's choice, e.g. the start of the day.
+type PostPlansRequest struct {
+	Vehicles []PlanVehicle `json:"vehicles"`
+	Stops    []PlanStop    `json:"stops"`
+	// Seed makes the plan reproducible, the same request always gets the same plan
+	Seed       uint64 `json:"seed,omitempty"`
+	CoordOrder string `json:"coord_order,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+}
+
+type PlanVehicle struct {
+	ID    string   `json:"id"`
+	Start Location `json:"start"`
+	// End defaults to Start
+	End Location `json:"end,omitempty"`
+	// Capacity is the max total demand of the stops on the route, unlimited if 0
+	Capacity float64     `json:"capacity,omitempty"`
+	Shift    *TimeWindow `json:"shift,omitempty"`
+}
+
+type PlanStop struct {
+	ID          string      `json:"id"`
+	Location    Location    `json:"location"`
+	Demand      float64     `json:"demand,omitempty"`
+	TimeWindow  *TimeWindow `json:"time_window,omitempty"`
+	ServiceTime float64     `json:"service_time,omitempty"`
+}
+
+// TimeWindow is an interval of time, End is unbounded if 0
+type TimeWindow struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end,omitempty"`
+}
+
+type PostPlansResponse struct {
+	Routes []PlanRoute `json:"routes"`
+	// Unassigned lists the IDs of the stops no vehicle can visit
+	Unassigned []string `json:"unassigned"`
+	Distance   float64  `json:"distance"`
+	Duration   float64  `json:"duration"`
+}
+
+type PlanRoute struct {
+	Vehicle   string      `json:"vehicle"`
+	Stops     []PlanVisit `json:"stops"`
+	Departure float64     `json:"departure"`
+	Return    float64     `json:"return"`
+	Distance  float64     `json:"distance"`
+	Duration  float64     `json:"duration"`
+	Load      float64     `json:"load"`
+}
+
+type PlanVisit struct {
+	ID       string   `json:"id"`
+	Location Location `json:"location"`
+	Arrival  float64  `json:"arrival"`
+	// Start is when the service begins, after waiting for the time window to open
+	Start     float64 `json:"start"`
+	Departure float64 `json:"departure"`
+}
+
+// PickupPointRequest is the JSON body accepted by POST and PUT /pickup-points
+type PickupPointRequest struct {
+	// ID is generated by POST if empty
+	ID           string   `json:"id,omitempty"`
+	ExternalID   string   `json:"external_id,omitempty"`
+	Name         string   `json:"name"`
+	Location     Location `json:"location"`
+	Carrier      string   `json:"carrier,omitempty"`
+	OpeningHours string   `json:"opening_hours,omitempty"`
+	Capacity     int      `json:"capacity,omitempty"`
+	CoordOrder   string   `json:"coord_order,omitempty"`
+}
+
+type PickupPoint struct {
+	ID           string    `json:"id"`
+	ExternalID   string    `json:"external_id,omitempty"`
+	Name         string    `json:"name"`
+	Location     Location  `json:"location"`
+	Carrier      string    `json:"carrier,omitempty"`
+	OpeningHours string    `json:"opening_hours,omitempty"`
+	Capacity     int       `json:"capacity,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ListPickupPointsResponse struct {
+	PickupPoints []*PickupPoint `json:"pickup_points"`
+}
+
+type ImportPickupPointsRequest struct {
+	Format string
+	DryRun bool
+}
+
+type ExportPickupPointsRequest struct {
+	Format string
+}
+
+type ImportPickupPointsResponse struct {
+	DryRun bool `json:"dry_run"`
+	// Created and Updated count the pickup points created and updated, or that would be on a dry run or with errors
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Errors  []*ImportRowError `json:"errors"`
+}
+
+type ImportRowError struct {
+	// Row is the line of a CSV record, the header being line 1, or the position of a GeoJSON feature starting at 1
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+type NearestPickupPointsRequest struct {
+	Source Location
+	// Count is the number of pickup points to return
+	Count int
+	// Radius is the max great-circle distance in kilometers, 0 means no limit
+	Radius     float64
+	CoordOrder string
+	Profile    string
+}
+
+// NearestPickupPointsResponse holds the routes to the nearest pickup points by driving time, fastest first
+type NearestPickupPointsResponse struct {
+	Source Location `json:"source"`
+	Routes []*Route `json:"routes"`
 }
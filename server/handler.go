@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mrasoolmirzaei/delivery-route-system/registry"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
 )
 
@@ -18,25 +22,20 @@ func (s *Server) health() http.HandlerFunc {
 			"service": "delivery-route-system",
 		}
 
-		// Check OSRM dependency if routeService is available
-		if s.routeService != nil {
+		// Check the OSRM dependency directly, the route service may answer from its cache or with estimates
+		if s.osrm != nil {
 			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 			defer cancel()
 
-			// Test with a simple health check location
-			testSource := service.Location("13,14")
-			testDest := []service.Location{service.Location("13.1234,12.7890")}
-
-			_, err := s.routeService.GetFastestRoutes(ctx, testSource, testDest)
-			if err != nil {
-				// Check if it's a timeout or context cancellation
-				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-					status["osrm"] = "timeout"
-					status["status"] = "degraded"
-				}
+			err := s.osrm.Ping(ctx)
+			switch {
+			case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+				status["osrm"] = "timeout"
+				status["status"] = "degraded"
+			case err != nil:
 				status["osrm"] = "unavailable"
 				status["status"] = "degraded"
-			} else {
+			default:
 				status["osrm"] = "healthy"
 			}
 		}
@@ -59,46 +58,803 @@ func (s *Server) getRoutes() http.HandlerFunc {
 			return
 		}
 
-		source := service.Location(req.Source)
-		destinations := make([]service.Location, len(req.Destinations))
-		for i, dst := range req.Destinations {
-			destinations[i] = service.Location(dst)
+		order := coordOrder(req.CoordOrder)
+		located := make([]destination, len(req.Destinations))
+		for i, l := range req.Destinations {
+			located[i] = destination{location: l}
+			if len(req.DestinationRefs) > 0 {
+				located[i].id = req.DestinationRefs[i]
+			}
+		}
+		destinations, validationErr := s.resolveDestinations(r.Context(), located, req.DestinationIDs, "dst_id", order)
+		if validationErr != nil {
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		opts := routeOptions(req.Profile, req.MaxSnapDistance, req.SnapPolicy)
+		opts.Limit, opts.MaxDuration, opts.MaxDistance = req.Limit, req.MaxDuration, req.MaxDistance
+		opts.Ranking = service.RankingStrategy(req.Ranking)
+		opts.DurationWeight, opts.DistanceWeight, opts.Tolerance = req.DurationWeight, req.DistanceWeight, req.Tolerance
+		s.writeFastestRoutes(w, r, req.Source, destinations, order, opts)
+	}
+}
+
+func (s *Server) postRoutes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, validationErr := validatePostRoutesRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate post routes request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		located := make([]destination, len(req.Destinations))
+		for i, d := range req.Destinations {
+			located[i] = destination{location: d.Location, id: d.ID, metadata: d.Metadata}
+		}
+		destinations, validationErr := s.resolveDestinations(r.Context(), located, req.DestinationIDs, "destination_ids", order)
+		if validationErr != nil {
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		opts := routeOptions(req.Profile, req.MaxSnapDistance, req.SnapPolicy)
+		opts.Limit, opts.MaxDuration, opts.MaxDistance = req.Limit, req.MaxDuration, req.MaxDistance
+		opts.Ranking = service.RankingStrategy(req.Ranking)
+		opts.DurationWeight, opts.DistanceWeight, opts.Tolerance = req.DurationWeight, req.DistanceWeight, req.Tolerance
+		s.writeFastestRoutes(w, r, req.Source, destinations, order, opts)
+	}
+}
+
+// destination is a destination of /routes, given either as a location or as a pickup point ID
+type destination struct {
+	location    Location
+	pickupPoint *registry.PickupPoint
+	// id and metadata are sent by the client with a location
+	id       string
+	metadata json.RawMessage
+}
+
+// resolveDestinations looks up the pickup points, whose locations are formatted in the requested order,
+// and appends them to the destinations given as locations. Unknown IDs are reported under key, e.g. dst_id[2].
+func (s *Server) resolveDestinations(ctx context.Context, located []destination, ids []string, key string, order service.CoordOrder) ([]destination, ValidationError) {
+	destinations := make([]destination, 0, len(located)+len(ids))
+	destinations = append(destinations, located...)
+
+	validationErr := ValidationError{}
+	for i, id := range ids {
+		p, err := s.pickupPoints.Get(ctx, id)
+		if err != nil {
+			validationErr[fmt.Sprintf("%s[%d]", key, i+1)] = fmt.Sprintf("pickup point %q not found", id)
+			continue
+		}
+		destinations = append(destinations, destination{location: formatLocation(p.Location, order), pickupPoint: p})
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+	return destinations, nil
+}
+
+// formatLocation formats a location of the service in the requested order
+func formatLocation(l service.Location, order service.CoordOrder) Location {
+	if c, err := l.Coordinate(); err == nil {
+		return Location(c.Format(order))
+	}
+	return Location(l)
+}
+
+func coordOrder(order string) service.CoordOrder {
+	if order == "" {
+		return service.CoordOrderLatLon
+	}
+	return service.CoordOrder(order)
+}
+
+func routeOptions(profile string, maxSnapDistance float64, snapPolicy string) *service.RouteOptions {
+	opts := &service.RouteOptions{
+		Profile:         service.Profile(profile),
+		MaxSnapDistance: maxSnapDistance,
+		SnapPolicy:      service.SnapPolicyFlag,
+	}
+	if snapPolicy != "" {
+		opts.SnapPolicy = service.SnapPolicy(snapPolicy)
+	}
+	return opts
+}
+
+// routeEstimateHeader is set to "approximate" on responses whose routes were estimated while OSRM was unavailable
+const routeEstimateHeader = "X-Route-Estimate"
+
+// writeFastestRoutes queries the route service and writes the sorted routes, shared by GET and POST /routes
+func (s *Server) writeFastestRoutes(w http.ResponseWriter, r *http.Request, src Location, dsts []destination, order service.CoordOrder, opts *service.RouteOptions) {
+	response, ok := s.fastestRoutes(w, r, src, dsts, order, opts)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// fastestRoutes queries the route service and returns the sorted routes, it writes the error response and returns false on failure.
+// Locations are converted to the service's latitude,longitude order and the destinations are reported back as sent.
+func (s *Server) fastestRoutes(w http.ResponseWriter, r *http.Request, src Location, dsts []destination, order service.CoordOrder, opts *service.RouteOptions) (*GetRoutesResponse, bool) {
+	sourceCoord, err := service.ParseLocation(src.String(), order)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ValidationError{"src": err.Error()})
+		return nil, false
+	}
+	source := sourceCoord.Location()
+
+	destinations := make([]service.Destination, len(dsts))
+	for i, dst := range dsts {
+		c, err := service.ParseLocation(dst.location.String(), order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{fmt.Sprintf("dst[%d]", i+1): err.Error()})
+			return nil, false
+		}
+		destinations[i] = service.Destination{Location: c.Location(), ID: dst.id}
+	}
+
+	result, err := s.routeService.GetFastestRoutes(r.Context(), source, destinations, opts)
+	if err != nil {
+		s.log.WithError(err).Error("failed to get routes")
+		writeServiceError(w, err)
+		return nil, false
+	}
+
+	serverRoutes := make([]*Route, len(result.Routes))
+	for i, route := range result.Routes {
+		// The destination is reported back as sent by the client
+		dst := dsts[route.Index]
+		serverRoutes[i] = &Route{
+			Destination:     dst.location,
+			ID:              route.ID,
+			Metadata:        dst.metadata,
+			Index:           route.Index,
+			Distance:        route.Distance,
+			Duration:        route.Duration,
+			SnappedLocation: formatLocation(route.SnappedLocation, order),
+			SnapDistance:    route.SnapDistance,
+			SnapTooFar:      route.SnapTooFar,
+			Unreachable:     route.Unreachable,
+			Score:           route.Score,
+			Estimated:       route.Estimated,
+		}
+		if dst.pickupPoint != nil {
+			serverRoutes[i].PickupPoint = toPickupPoint(dst.pickupPoint, order)
+		}
+	}
+
+	if result.Estimated {
+		w.Header().Set(routeEstimateHeader, "approximate")
+	}
+
+	response := &GetRoutesResponse{
+		Source:  src,
+		Ranking: string(result.Ranking),
+		Routes:  serverRoutes,
+	}
+	if filtered := result.Filtered; filtered.Total() > 0 {
+		response.Filtered = &FilteredRoutes{
+			Total:       filtered.Total(),
+			SnapTooFar:  filtered.SnapTooFar,
+			Unreachable: filtered.Unreachable,
+			MaxDuration: filtered.MaxDuration,
+			MaxDistance: filtered.MaxDistance,
+			Limit:       filtered.Limit,
+		}
+	}
+	return response, true
+}
+
+func (s *Server) getRoute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, validationErr := validateGetRouteRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate get route request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
 		}
 
-		serviceRoutes, err := s.routeService.GetFastestRoutes(r.Context(), source, destinations)
+		order := coordOrder(req.CoordOrder)
+		locations, err := toServiceLocations([]Location{req.Source, req.Destination}, order)
 		if err != nil {
-			s.log.WithError(err).Error("failed to get routes")
+			writeJSON(w, http.StatusBadRequest, ValidationError{"location": err.Error()})
+			return
+		}
 
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				writeJSON(w, http.StatusRequestTimeout, map[string]string{
-					"error": "request timeout",
-				})
+		opts := &service.RouteOptions{
+			Profile:  service.Profile(req.Profile),
+			Geometry: service.GeometryFormat(req.Geometry),
+			Steps:    req.Steps,
+		}
+		route, err := s.routeService.GetRoute(r.Context(), locations[0], locations[1], opts)
+		if err != nil {
+			s.log.WithError(err).Error("failed to get route")
+			writeServiceError(w, err)
+			return
+		}
+
+		response := &GetRouteResponse{
+			Source:      req.Source,
+			Destination: req.Destination,
+			Distance:    route.Distance,
+			Duration:    route.Duration,
+			Geometry:    route.Geometry.Polyline,
+		}
+
+		if opts.Geometry == service.GeometryGeoJSON {
+			line := &LineString{Type: "LineString", Coordinates: make([][2]float64, len(route.Geometry.Coordinates))}
+			for i, c := range route.Geometry.Coordinates {
+				line.Coordinates[i] = [2]float64{c.Lon, c.Lat}
+			}
+			response.Geometry = line
+		}
+
+		if req.Steps {
+			response.Steps = make([]RouteStep, len(route.Steps))
+			for i, step := range route.Steps {
+				response.Steps[i] = RouteStep{
+					Instruction: step.Instruction,
+					Name:        step.Name,
+					Distance:    step.Distance,
+					Duration:    step.Duration,
+					Maneuver:    step.Maneuver,
+					Modifier:    step.Modifier,
+					Location:    Location(step.Location.Format(order)),
+				}
+			}
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func (s *Server) postMatrix() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, validationErr := validatePostMatrixRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate post matrix request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		sources, err := toServiceLocations(req.Sources, order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"sources": err.Error()})
+			return
+		}
+		destinations, err := toServiceLocations(req.Destinations, order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"destinations": err.Error()})
+			return
+		}
+
+		matrix, err := s.routeService.GetMatrix(r.Context(), sources, destinations, &service.RouteOptions{Profile: service.Profile(req.Profile)})
+		if err != nil {
+			s.log.WithError(err).Error("failed to get matrix")
+			writeServiceError(w, err)
+			return
+		}
+
+		cells := make([][]MatrixCell, len(matrix.Cells))
+		for i, row := range matrix.Cells {
+			cells[i] = make([]MatrixCell, len(row))
+			for j, cell := range row {
+				cells[i][j] = MatrixCell{
+					Distance:    cell.Distance,
+					Duration:    cell.Duration,
+					Unreachable: cell.Unreachable,
+				}
+			}
+		}
+
+		writeJSON(w, http.StatusOK, &PostMatrixResponse{
+			Sources:      req.Sources,
+			Destinations: req.Destinations,
+			Cells:        cells,
+		})
+	}
+}
+
+func (s *Server) postTrips() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, validationErr := validatePostTripsRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate post trips request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		locations := append([]Location{req.Source}, req.Stops...)
+		if req.End != "" {
+			locations = append(locations, req.End)
+		}
+		converted, err := toServiceLocations(locations, order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"locations": err.Error()})
+			return
+		}
+
+		opts := &service.TripOptions{
+			Profile: service.Profile(req.Profile),
+			OneWay:  req.Roundtrip != nil && !*req.Roundtrip,
+		}
+		if req.End != "" {
+			opts.End = converted[len(converted)-1]
+		}
+		trip, err := s.routeService.GetTrip(r.Context(), converted[0], converted[1:len(req.Stops)+1], opts)
+		if err != nil {
+			s.log.WithError(err).Error("failed to get trip")
+			writeServiceError(w, err)
+			return
+		}
+
+		// visits are the locations in visiting order, as sent by the client
+		visits := []Location{req.Source}
+		stops := make([]Location, len(trip.Order))
+		for i, stop := range trip.Order {
+			stops[i] = req.Stops[stop]
+		}
+		visits = append(visits, stops...)
+		if req.End != "" {
+			visits = append(visits, req.End)
+		}
+
+		legs := make([]TripLeg, len(trip.Legs))
+		for i, leg := range trip.Legs {
+			legs[i] = TripLeg{
+				From:     visits[i],
+				To:       visits[(i+1)%len(visits)],
+				Distance: leg.Distance,
+				Duration: leg.Duration,
+			}
+		}
+
+		writeJSON(w, http.StatusOK, &PostTripsResponse{
+			Source:   req.Source,
+			Order:    trip.Order,
+			Stops:    stops,
+			Legs:     legs,
+			Distance: trip.Distance,
+			Duration: trip.Duration,
+		})
+	}
+}
+
+func (s *Server) postPlans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, validationErr := validatePostPlansRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate post plans request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		vehicles := make([]service.PlanVehicle, len(req.Vehicles))
+		for i, v := range req.Vehicles {
+			end := v.End
+			if end == "" {
+				end = v.Start
+			}
+			locations, err := toServiceLocations([]Location{v.Start, end}, order)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, ValidationError{fmt.Sprintf("vehicles[%d]", i+1): err.Error()})
 				return
 			}
+			vehicles[i] = service.PlanVehicle{Start: locations[0], End: locations[1], Capacity: v.Capacity, Shift: timeWindow(v.Shift)}
+		}
 
-			writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-				"error": getErrorMessage(err),
-			})
+		stopLocations := make([]Location, len(req.Stops))
+		for i, stop := range req.Stops {
+			stopLocations[i] = stop.Location
+		}
+		locations, err := toServiceLocations(stopLocations, order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"stops": err.Error()})
 			return
 		}
+		stops := make([]service.PlanStop, len(req.Stops))
+		for i, stop := range req.Stops {
+			stops[i] = service.PlanStop{Location: locations[i], Demand: stop.Demand, Window: timeWindow(stop.TimeWindow), ServiceTime: stop.ServiceTime}
+		}
 
-		serverRoutes := make([]*Route, len(serviceRoutes))
-		for i, route := range serviceRoutes {
-			serverRoutes[i] = &Route{
-				Destination: Location(route.Destination),
-				Distance:    route.Distance,
-				Duration:    route.Duration,
+		plan, err := s.routeService.GetPlan(r.Context(), vehicles, stops, &service.PlanOptions{Profile: service.Profile(req.Profile), Seed: req.Seed})
+		if err != nil {
+			s.log.WithError(err).Error("failed to get plan")
+			writeServiceError(w, err)
+			return
+		}
+
+		response := &PostPlansResponse{
+			Routes:     make([]PlanRoute, len(plan.Routes)),
+			Unassigned: make([]string, len(plan.Unassigned)),
+			Distance:   plan.Distance,
+			Duration:   plan.Duration,
+		}
+		for i, route := range plan.Routes {
+			visits := make([]PlanVisit, len(route.Visits))
+			for j, visit := range route.Visits {
+				visits[j] = PlanVisit{
+					ID:        req.Stops[visit.Stop].ID,
+					Location:  req.Stops[visit.Stop].Location,
+					Arrival:   visit.Arrival,
+					Start:     visit.Start,
+					Departure: visit.Departure,
+				}
+			}
+			response.Routes[i] = PlanRoute{
+				Vehicle:   req.Vehicles[route.Vehicle].ID,
+				Stops:     visits,
+				Departure: route.Departure,
+				Return:    route.Return,
+				Distance:  route.Distance,
+				Duration:  route.Duration,
+				Load:      route.Load,
+			}
+		}
+		for i, stop := range plan.Unassigned {
+			response.Unassigned[i] = req.Stops[stop].ID
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func timeWindow(w *TimeWindow) service.TimeWindow {
+	if w == nil {
+		return service.TimeWindow{}
+	}
+	return service.TimeWindow{Start: w.Start, End: w.End}
+}
+
+func (s *Server) createPickupPoint() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, validationErr := validatePickupPointRequest(r, "")
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate create pickup point request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		p, err := fromPickupPointRequest(req, order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"location": err.Error()})
+			return
+		}
+		if err := s.pickupPoints.Create(r.Context(), p); err != nil {
+			s.log.WithError(err).Error("failed to create pickup point")
+			writeRegistryError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/pickup-points/"+p.ID)
+		writeJSON(w, http.StatusCreated, toPickupPoint(p, order))
+	}
+}
+
+func (s *Server) listPickupPoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, validationErr := queryCoordOrder(r)
+		if validationErr != nil {
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		points, err := s.pickupPoints.List(r.Context())
+		if err != nil {
+			s.log.WithError(err).Error("failed to list pickup points")
+			writeRegistryError(w, err)
+			return
+		}
+
+		response := &ListPickupPointsResponse{PickupPoints: make([]*PickupPoint, len(points))}
+		for i, p := range points {
+			response.PickupPoints[i] = toPickupPoint(p, order)
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func (s *Server) getPickupPoint() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, validationErr := queryCoordOrder(r)
+		if validationErr != nil {
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		p, err := s.pickupPoints.Get(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeRegistryError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toPickupPoint(p, order))
+	}
+}
+
+func (s *Server) updatePickupPoint() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		req, validationErr := validatePickupPointRequest(r, r.PathValue("id"))
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate update pickup point request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		p, err := fromPickupPointRequest(req, order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"location": err.Error()})
+			return
+		}
+		if err := s.pickupPoints.Update(r.Context(), p); err != nil {
+			s.log.WithError(err).Error("failed to update pickup point")
+			writeRegistryError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, toPickupPoint(p, order))
+	}
+}
+
+func (s *Server) deletePickupPoint() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.pickupPoints.Delete(r.Context(), r.PathValue("id")); err != nil {
+			writeRegistryError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// importPickupPoints creates or updates the pickup points of a CSV or GeoJSON file, matched by external ID.
+// Nothing is written on a dry run or if any row is invalid, in which case the rows are reported with 400.
+func (s *Server) importPickupPoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+		req, validationErr := validateImportPickupPointsRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate import pickup points request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		result, err := registry.Import(r.Context(), s.pickupPoints, r.Body, registry.Format(req.Format), &registry.ImportOptions{DryRun: req.DryRun})
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				writeJSON(w, http.StatusBadRequest, ValidationError{"body": fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)})
+			case errors.Is(err, registry.ErrInvalidImport):
+				writeJSON(w, http.StatusBadRequest, ValidationError{"body": err.Error()})
+			default:
+				s.log.WithError(err).Error("failed to import pickup points")
+				writeRegistryError(w, err)
 			}
+			return
 		}
 
-		response := &GetRoutesResponse{
-			Source: req.Source,
-			Routes: serverRoutes,
+		response := &ImportPickupPointsResponse{
+			DryRun:  req.DryRun,
+			Created: result.Created,
+			Updated: result.Updated,
+			Errors:  make([]*ImportRowError, len(result.Errors)),
+		}
+		for i, rowErr := range result.Errors {
+			response.Errors[i] = &ImportRowError{Row: rowErr.Row, ExternalID: rowErr.ExternalID, Error: rowErr.Message}
 		}
+
+		status := http.StatusOK
+		if len(response.Errors) > 0 && !req.DryRun {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, response)
+	}
+}
+
+func (s *Server) exportPickupPoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, validationErr := validateExportPickupPointsRequest(r)
+		if validationErr != nil {
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		points, err := s.pickupPoints.List(r.Context())
+		if err != nil {
+			s.log.WithError(err).Error("failed to list pickup points")
+			writeRegistryError(w, err)
+			return
+		}
+
+		format := registry.Format(req.Format)
+		contentType, extension := "text/csv; charset=utf-8", "csv"
+		if format == registry.FormatGeoJSON {
+			contentType, extension = "application/geo+json", "geojson"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pickup-points.%s"`, extension))
+		if err := registry.Export(w, points, format); err != nil {
+			s.log.WithError(err).Error("failed to export pickup points")
+		}
+	}
+}
+
+// nearestPickupPoints prefilters the pickup points closest to the source by great-circle distance,
+// then ranks them by driving time. The multiplier sets how many candidates are routed per requested result.
+func (s *Server) nearestPickupPoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, validationErr := validateNearestPickupPointsRequest(r)
+		if validationErr != nil {
+			s.log.WithError(validationErr).Error("failed to validate nearest pickup points request")
+			writeJSON(w, http.StatusBadRequest, validationErr)
+			return
+		}
+
+		order := coordOrder(req.CoordOrder)
+		source, err := service.ParseLocation(req.Source.String(), order)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ValidationError{"src": err.Error()})
+			return
+		}
+
+		candidates := min(req.Count*s.nearestCandidateMultiplier, maxDstPOST)
+		neighbors, err := s.pickupPoints.Nearest(r.Context(), source, candidates, req.Radius*1000)
+		if err != nil {
+			s.log.WithError(err).Error("failed to search pickup points")
+			writeRegistryError(w, err)
+			return
+		}
+
+		response := &NearestPickupPointsResponse{Source: req.Source, Routes: make([]*Route, 0)}
+		if len(neighbors) == 0 {
+			writeJSON(w, http.StatusOK, response)
+			return
+		}
+
+		destinations := make([]destination, len(neighbors))
+		for i, n := range neighbors {
+			destinations[i] = destination{location: formatLocation(n.PickupPoint.Location, order), pickupPoint: n.PickupPoint}
+		}
+
+		opts := routeOptions(req.Profile, 0, "")
+		opts.Limit = req.Count
+		routes, ok := s.fastestRoutes(w, r, req.Source, destinations, order, opts)
+		if !ok {
+			return
+		}
+		response.Routes = routes.Routes
 		writeJSON(w, http.StatusOK, response)
 	}
 }
 
+// queryCoordOrder reads the optional coord_order query parameter of GET requests
+func queryCoordOrder(r *http.Request) (service.CoordOrder, ValidationError) {
+	validationErr := ValidationError{}
+	order := validateCoordOrder(r.URL.Query().Get("coord_order"), validationErr)
+	if len(validationErr) > 0 {
+		return "", validationErr
+	}
+	return order, nil
+}
+
+func fromPickupPointRequest(req *PickupPointRequest, order service.CoordOrder) (*registry.PickupPoint, error) {
+	c, err := service.ParseLocation(req.Location.String(), order)
+	if err != nil {
+		return nil, err
+	}
+	return &registry.PickupPoint{
+		ID:           req.ID,
+		ExternalID:   req.ExternalID,
+		Name:         strings.TrimSpace(req.Name),
+		Location:     c.Location(),
+		Carrier:      req.Carrier,
+		OpeningHours: req.OpeningHours,
+		Capacity:     req.Capacity,
+	}, nil
+}
+
+func toPickupPoint(p *registry.PickupPoint, order service.CoordOrder) *PickupPoint {
+	return &PickupPoint{
+		ID:           p.ID,
+		ExternalID:   p.ExternalID,
+		Name:         p.Name,
+		Location:     formatLocation(p.Location, order),
+		Carrier:      p.Carrier,
+		OpeningHours: p.OpeningHours,
+		Capacity:     p.Capacity,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+	}
+}
+
+// writeRegistryError maps errors returned by the pickup point store to an HTTP error response
+func writeRegistryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, registry.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "pickup point not found"})
+	case errors.Is(err, registry.ErrAlreadyExists):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "pickup point already exists"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "pickup point storage failed"})
+	}
+}
+
+// toServiceLocations converts locations given in the given order to the service's latitude,longitude order
+func toServiceLocations(locations []Location, order service.CoordOrder) ([]service.Location, error) {
+	converted := make([]service.Location, len(locations))
+	for i, l := range locations {
+		c, err := service.ParseLocation(l.String(), order)
+		if err != nil {
+			return nil, fmt.Errorf("location number %d is invalid: %w", i+1, err)
+		}
+		converted[i] = c.Location()
+	}
+	return converted, nil
+}
+
+// retryAfterError is implemented by errors telling when the upstream service can be called again,
+// e.g. when its circuit breaker is open
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// writeServiceError maps errors returned by the route service to an HTTP error response
+func writeServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrUnsupportedProfile) {
+		writeJSON(w, http.StatusBadRequest, ValidationError{
+			"profile": "profile is not supported by this deployment",
+		})
+		return
+	}
+
+	if errors.Is(err, service.ErrNoRoute) {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "no route found between source and destination",
+		})
+		return
+	}
+
+	if errors.Is(err, service.ErrNoTrip) {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "no trip found visiting all stops",
+		})
+		return
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		writeJSON(w, http.StatusRequestTimeout, map[string]string{
+			"error": "request timeout",
+		})
+		return
+	}
+
+	var retryAfterErr retryAfterError
+	if errors.As(err, &retryAfterErr) {
+		seconds := int(math.Ceil(retryAfterErr.RetryAfter().Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	}
+
+	writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+		"error": getErrorMessage(err),
+	})
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -113,6 +869,11 @@ func getErrorMessage(err error) string {
 		return "unknown error"
 	}
 
+	var retryAfterErr retryAfterError
+	if errors.As(err, &retryAfterErr) {
+		return "routing service unavailable, retry later"
+	}
+
 	errStr := err.Error()
 	if containsAny(errStr, []string{"connection refused", "timeout", "context deadline"}) {
 		return "service temporarily unavailable"
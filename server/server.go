@@ -6,13 +6,17 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/mrasoolmirzaei/delivery-route-system/registry"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	defaultRequestTimeout  = 30 * time.Second
 	defaultShutdownTimeout = 5 * time.Second
+
+	defaultNearestCandidateMultiplier = 3
 )
 
 type Server struct {
@@ -20,15 +24,33 @@ type Server struct {
 	router          *http.ServeMux
 	stopChan        chan struct{}
 	routeService    service.RouteService
+	osrm            Pinger
+	pickupPoints    *registry.IndexedStore
 	requestTimeout  time.Duration
 	shutdownTimeout time.Duration
+
+	nearestCandidateMultiplier int
+}
+
+// Pinger checks that a dependency is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
 
 type Config struct {
-	Logger          logrus.FieldLogger
-	RouteService    service.RouteService
+	Logger       logrus.FieldLogger
+	RouteService service.RouteService
+	// OSRM is checked by /health. It should be the OSRM client itself rather than the route service,
+	// so that cached or estimated routes don't hide an outage. The check is skipped when nil.
+	OSRM Pinger
+	// PickupPoints stores the pickup points routes can be requested to by ID, defaults to an in-memory store.
+	// It is wrapped in a registry.IndexedStore for nearest searches, unless it is one already.
+	PickupPoints    registry.Store
 	RequestTimeout  time.Duration
 	ShutdownTimeout time.Duration
+	// NearestCandidateMultiplier is how many of the closest pickup points by great-circle distance are routed
+	// per requested result of /pickup-points/nearest, defaults to 3. Higher is more accurate but costs more OSRM queries.
+	NearestCandidateMultiplier int
 }
 
 func NewServer(config Config) (*Server, error) {
@@ -49,13 +71,31 @@ func NewServer(config Config) (*Server, error) {
 		shutdownTimeout = config.ShutdownTimeout
 	}
 
+	if config.PickupPoints == nil {
+		config.PickupPoints = registry.NewMemoryStore()
+	}
+	// Changes made to a shared indexed store outside the server must invalidate the index the server searches
+	pickupPoints, ok := config.PickupPoints.(*registry.IndexedStore)
+	if !ok {
+		pickupPoints = registry.NewIndexedStore(config.PickupPoints)
+	}
+
+	nearestCandidateMultiplier := defaultNearestCandidateMultiplier
+	if config.NearestCandidateMultiplier > 0 {
+		nearestCandidateMultiplier = config.NearestCandidateMultiplier
+	}
+
 	s := &Server{
 		log:             config.Logger,
 		router:          http.NewServeMux(),
 		stopChan:        make(chan struct{}),
 		routeService:    config.RouteService,
+		osrm:            config.OSRM,
+		pickupPoints:    pickupPoints,
 		requestTimeout:  requestTimeout,
 		shutdownTimeout: shutdownTimeout,
+
+		nearestCandidateMultiplier: nearestCandidateMultiplier,
 	}
 
 	s.SetupRoutes()
@@ -63,8 +103,27 @@ func NewServer(config Config) (*Server, error) {
 }
 
 func (s *Server) SetupRoutes() {
-	s.router.HandleFunc("GET /health", s.health())
-	s.router.HandleFunc("GET /routes", s.getRoutes())
+	s.handle("GET /health", s.health())
+	s.handle("GET /routes", s.getRoutes())
+	s.handle("GET /route", s.getRoute())
+	s.handle("POST /routes", s.postRoutes())
+	s.handle("POST /matrix", s.postMatrix())
+	s.handle("POST /trips", s.postTrips())
+	s.handle("POST /plans", s.postPlans())
+	s.handle("POST /pickup-points", s.createPickupPoint())
+	s.handle("GET /pickup-points", s.listPickupPoints())
+	s.handle("GET /pickup-points/nearest", s.nearestPickupPoints())
+	s.handle("POST /pickup-points/import", s.importPickupPoints())
+	s.handle("GET /pickup-points/export", s.exportPickupPoints())
+	s.handle("GET /pickup-points/{id}", s.getPickupPoint())
+	s.handle("PUT /pickup-points/{id}", s.updatePickupPoint())
+	s.handle("DELETE /pickup-points/{id}", s.deletePickupPoint())
+	s.handle("GET /metrics", promhttp.Handler())
+}
+
+// handle registers the handler for the pattern, recording request metrics and a trace span named after the pattern
+func (s *Server) handle(pattern string, handler http.Handler) {
+	s.router.Handle(pattern, s.tracingMiddleware(pattern, s.metricsMiddleware(pattern, handler)))
 }
 
 func (s *Server) Serve(listen string) error {
@@ -1,16 +1,42 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/mrasoolmirzaei/delivery-route-system/registry"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
 )
 
 const (
-	maxURLChars = 2048
-	maxDstGET   = 80
+	maxURLChars  = 2048
+	maxDstGET    = 80
+	maxBodyBytes = 1 << 20 // 1 MiB
+	maxDstPOST   = 1000
+
+	maxSrcMatrix   = 100
+	maxDstMatrix   = 1000
+	maxCellsMatrix = 25000
+
+	// OSRM's default trip size limit is 100 coordinates, including the source and the end
+	maxStopsTrip = 98
+
+	maxVehiclesPlan = 50
+	maxStopsPlan    = 200
+
+	defaultNearestCount = 5
+	maxNearestCount     = 50
+
+	maxImportBytes = 16 << 20 // 16 MiB
 )
 
 type ValidationError map[string]string
@@ -32,6 +58,9 @@ func validateGetRoutesRequest(r *http.Request) (*GetRoutesRequest, ValidationErr
 	request := &GetRoutesRequest{}
 	params := r.URL.Query()
 
+	request.CoordOrder = params.Get("coord_order")
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+
 	source := params.Get("src")
 	if source == "" {
 		validationErr["src"] = "source location is required"
@@ -39,37 +68,652 @@ func validateGetRoutesRequest(r *http.Request) (*GetRoutesRequest, ValidationErr
 
 	if source != "" {
 		request.Source = Location(source)
-		// Convert to service.Location for domain validation
-		serviceLocation := service.Location(source)
-		if err := serviceLocation.Validate(); err != nil {
+		if _, err := service.ParseLocation(source, order); err != nil {
 			validationErr["src"] = err.Error()
 		}
 	}
 
 	// Get all destination parameters (can be multiple)
 	destinations := params["dst"]
-	if len(destinations) == 0 {
+	request.DestinationIDs = params["dst_id"]
+	if len(destinations)+len(request.DestinationIDs) == 0 {
 		validationErr["dst"] = "destination location is required"
 	}
 
-	if len(destinations) > maxDstGET {
-		validationErr["dst"] = fmt.Sprintf("too many destinations: %d, max is %d", len(destinations), maxDstGET)
+	if count := len(destinations) + len(request.DestinationIDs); count > maxDstGET {
+		validationErr["dst"] = fmt.Sprintf("too many destinations: %d, max is %d", count, maxDstGET)
+	}
+
+	for i, id := range request.DestinationIDs {
+		if id == "" {
+			validationErr[fmt.Sprintf("dst_id[%d]", i+1)] = fmt.Sprintf("destination id number %d is empty", i+1)
+		}
 	}
 
 	request.Destinations = make([]Location, len(destinations))
 	for i, dst := range destinations {
 		request.Destinations[i] = Location(dst)
-		// Convert to service.Location for domain validation
-		serviceLocation := service.Location(dst)
-		if err := serviceLocation.Validate(); err != nil {
+		if _, err := service.ParseLocation(dst, order); err != nil {
 			dstKey := fmt.Sprintf("dst[%d]", i+1)
 			validationErr[dstKey] = fmt.Sprintf("destination number %d is invalid: %s", i+1, err.Error())
 		}
 	}
 
+	request.DestinationRefs = params["dst_ref"]
+	if len(request.DestinationRefs) > 0 && len(request.DestinationRefs) != len(destinations) {
+		validationErr["dst_ref"] = fmt.Sprintf("got %d destination refs for %d destinations", len(request.DestinationRefs), len(destinations))
+	}
+
+	if maxSnapDistance := params.Get("max_snap_distance"); maxSnapDistance != "" {
+		v, err := strconv.ParseFloat(maxSnapDistance, 64)
+		if err != nil {
+			validationErr["max_snap_distance"] = "max snap distance must be a number"
+		} else {
+			request.MaxSnapDistance = v
+		}
+	}
+	request.SnapPolicy = params.Get("snap_policy")
+	validateSnapOptions(request.MaxSnapDistance, request.SnapPolicy, validationErr)
+
+	if limit := params.Get("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil {
+			validationErr["limit"] = "limit must be a whole number"
+		} else {
+			request.Limit = v
+		}
+	}
+	request.MaxDuration = floatParam(params, "max_duration", validationErr)
+	request.MaxDistance = floatParam(params, "max_distance", validationErr)
+	validateFilterOptions(request.Limit, request.MaxDuration, request.MaxDistance, validationErr)
+
+	request.Ranking = params.Get("ranking")
+	request.DurationWeight = floatParam(params, "duration_weight", validationErr)
+	request.DistanceWeight = floatParam(params, "distance_weight", validationErr)
+	request.Tolerance = floatParam(params, "tolerance", validationErr)
+	validateRankingOptions(request.Ranking, request.DurationWeight, request.DistanceWeight, request.Tolerance, validationErr)
+
+	request.Profile = params.Get("profile")
+	validateProfile(request.Profile, validationErr)
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validateGetRouteRequest(r *http.Request) (*GetRouteRequest, ValidationError) {
+	validationErr := ValidationError{}
+	if len(r.URL.String()) > maxURLChars {
+		validationErr["url"] = fmt.Sprintf("URL is longer than %d characters", maxURLChars)
+	}
+
+	request := &GetRouteRequest{}
+	params := r.URL.Query()
+
+	request.CoordOrder = params.Get("coord_order")
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+
+	request.Source = Location(params.Get("src"))
+	if request.Source == "" {
+		validationErr["src"] = "source location is required"
+	} else if _, err := service.ParseLocation(request.Source.String(), order); err != nil {
+		validationErr["src"] = err.Error()
+	}
+
+	destinations := params["dst"]
+	switch {
+	case len(destinations) == 0:
+		validationErr["dst"] = "destination location is required"
+	case len(destinations) > 1:
+		validationErr["dst"] = "exactly one destination is allowed"
+	default:
+		request.Destination = Location(destinations[0])
+		if _, err := service.ParseLocation(destinations[0], order); err != nil {
+			validationErr["dst"] = err.Error()
+		}
+	}
+
+	request.Profile = params.Get("profile")
+	validateProfile(request.Profile, validationErr)
+
+	request.Geometry = params.Get("geometry")
+	if request.Geometry != "" {
+		if err := service.GeometryFormat(request.Geometry).Validate(); err != nil {
+			validationErr["geometry"] = err.Error()
+		}
+	}
+
+	if steps := params.Get("steps"); steps != "" {
+		v, err := strconv.ParseBool(steps)
+		if err != nil {
+			validationErr["steps"] = "steps must be true or false"
+		} else {
+			request.Steps = v
+		}
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validatePostRoutesRequest(r *http.Request) (*PostRoutesRequest, ValidationError) {
+	validationErr := ValidationError{}
+
+	request := &PostRoutesRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			validationErr["body"] = fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)
+		} else {
+			validationErr["body"] = fmt.Sprintf("invalid JSON body: %s", err.Error())
+		}
+		return nil, validationErr
+	}
+
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+
+	if request.Source == "" {
+		validationErr["source"] = "source location is required"
+	} else if _, err := service.ParseLocation(request.Source.String(), order); err != nil {
+		validationErr["source"] = err.Error()
+	}
+
+	if len(request.Destinations)+len(request.DestinationIDs) == 0 {
+		validationErr["destinations"] = "destination location is required"
+	}
+
+	if count := len(request.Destinations) + len(request.DestinationIDs); count > maxDstPOST {
+		validationErr["destinations"] = fmt.Sprintf("too many destinations: %d, max is %d", count, maxDstPOST)
+	}
+
+	for i, id := range request.DestinationIDs {
+		if id == "" {
+			validationErr[fmt.Sprintf("destination_ids[%d]", i+1)] = fmt.Sprintf("destination id number %d is empty", i+1)
+		}
+	}
+
+	for i, dst := range request.Destinations {
+		dstKey := fmt.Sprintf("destinations[%d]", i+1)
+		if _, err := service.ParseLocation(dst.Location.String(), order); err != nil {
+			validationErr[dstKey] = fmt.Sprintf("destination number %d is invalid: %s", i+1, err.Error())
+		} else if !isJSONObject(dst.Metadata) {
+			validationErr[dstKey] = fmt.Sprintf("metadata of destination number %d must be a JSON object", i+1)
+		}
+	}
+
+	validateSnapOptions(request.MaxSnapDistance, request.SnapPolicy, validationErr)
+	validateFilterOptions(request.Limit, request.MaxDuration, request.MaxDistance, validationErr)
+	validateRankingOptions(request.Ranking, request.DurationWeight, request.DistanceWeight, request.Tolerance, validationErr)
+	validateProfile(request.Profile, validationErr)
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validatePostMatrixRequest(r *http.Request) (*PostMatrixRequest, ValidationError) {
+	validationErr := ValidationError{}
+
+	request := &PostMatrixRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			validationErr["body"] = fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)
+		} else {
+			validationErr["body"] = fmt.Sprintf("invalid JSON body: %s", err.Error())
+		}
+		return nil, validationErr
+	}
+
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+	validateProfile(request.Profile, validationErr)
+
+	if len(request.Sources) == 0 {
+		validationErr["sources"] = "source location is required"
+	}
+	if len(request.Sources) > maxSrcMatrix {
+		validationErr["sources"] = fmt.Sprintf("too many sources: %d, max is %d", len(request.Sources), maxSrcMatrix)
+	}
+
+	if len(request.Destinations) == 0 {
+		validationErr["destinations"] = "destination location is required"
+	}
+	if len(request.Destinations) > maxDstMatrix {
+		validationErr["destinations"] = fmt.Sprintf("too many destinations: %d, max is %d", len(request.Destinations), maxDstMatrix)
+	}
+
+	if cells := len(request.Sources) * len(request.Destinations); cells > maxCellsMatrix {
+		validationErr["matrix"] = fmt.Sprintf("too many cells: %d, max is %d", cells, maxCellsMatrix)
+	}
+
+	for i, src := range request.Sources {
+		if _, err := service.ParseLocation(src.String(), order); err != nil {
+			validationErr[fmt.Sprintf("sources[%d]", i+1)] = fmt.Sprintf("source number %d is invalid: %s", i+1, err.Error())
+		}
+	}
+	for i, dst := range request.Destinations {
+		if _, err := service.ParseLocation(dst.String(), order); err != nil {
+			validationErr[fmt.Sprintf("destinations[%d]", i+1)] = fmt.Sprintf("destination number %d is invalid: %s", i+1, err.Error())
+		}
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validatePostTripsRequest(r *http.Request) (*PostTripsRequest, ValidationError) {
+	validationErr := ValidationError{}
+
+	request := &PostTripsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			validationErr["body"] = fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)
+		} else {
+			validationErr["body"] = fmt.Sprintf("invalid JSON body: %s", err.Error())
+		}
+		return nil, validationErr
+	}
+
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+	validateProfile(request.Profile, validationErr)
+
+	if request.Source == "" {
+		validationErr["source"] = "source location is required"
+	} else if _, err := service.ParseLocation(request.Source.String(), order); err != nil {
+		validationErr["source"] = err.Error()
+	}
+
+	if len(request.Stops) == 0 {
+		validationErr["stops"] = "at least one stop is required"
+	}
+	if len(request.Stops) > maxStopsTrip {
+		validationErr["stops"] = fmt.Sprintf("too many stops: %d, max is %d", len(request.Stops), maxStopsTrip)
+	}
+	for i, stop := range request.Stops {
+		if _, err := service.ParseLocation(stop.String(), order); err != nil {
+			validationErr[fmt.Sprintf("stops[%d]", i+1)] = fmt.Sprintf("stop number %d is invalid: %s", i+1, err.Error())
+		}
+	}
+
+	if request.End != "" {
+		if _, err := service.ParseLocation(request.End.String(), order); err != nil {
+			validationErr["end"] = err.Error()
+		}
+		if request.Roundtrip != nil && *request.Roundtrip {
+			validationErr["roundtrip"] = "a trip with a fixed end can't be a round trip"
+		}
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validatePostPlansRequest(r *http.Request) (*PostPlansRequest, ValidationError) {
+	validationErr := ValidationError{}
+
+	request := &PostPlansRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			validationErr["body"] = fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)
+		} else {
+			validationErr["body"] = fmt.Sprintf("invalid JSON body: %s", err.Error())
+		}
+		return nil, validationErr
+	}
+
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+	validateProfile(request.Profile, validationErr)
+
+	if len(request.Vehicles) == 0 {
+		validationErr["vehicles"] = "at least one vehicle is required"
+	}
+	if len(request.Vehicles) > maxVehiclesPlan {
+		validationErr["vehicles"] = fmt.Sprintf("too many vehicles: %d, max is %d", len(request.Vehicles), maxVehiclesPlan)
+	}
+	vehicleIDs := make(map[string]bool, len(request.Vehicles))
+	for i, v := range request.Vehicles {
+		key := fmt.Sprintf("vehicles[%d]", i+1)
+		switch {
+		case v.ID == "":
+			validationErr[key] = fmt.Sprintf("vehicle number %d has no id", i+1)
+		case vehicleIDs[v.ID]:
+			validationErr[key] = fmt.Sprintf("vehicle id %q is used more than once", v.ID)
+		case v.Start == "":
+			validationErr[key] = fmt.Sprintf("vehicle %q has no start location", v.ID)
+		case v.Capacity < 0:
+			validationErr[key] = fmt.Sprintf("vehicle %q has a negative capacity", v.ID)
+		}
+		vehicleIDs[v.ID] = true
+		for _, l := range []Location{v.Start, v.End} {
+			if _, err := service.ParseLocation(l.String(), order); l != "" && err != nil {
+				validationErr[key] = fmt.Sprintf("vehicle %q has an invalid location: %s", v.ID, err.Error())
+			}
+		}
+		if err := validateTimeWindow(v.Shift); err != nil {
+			validationErr[key] = fmt.Sprintf("vehicle %q has an invalid shift: %s", v.ID, err.Error())
+		}
+	}
+
+	if len(request.Stops) == 0 {
+		validationErr["stops"] = "at least one stop is required"
+	}
+	if len(request.Stops) > maxStopsPlan {
+		validationErr["stops"] = fmt.Sprintf("too many stops: %d, max is %d", len(request.Stops), maxStopsPlan)
+	}
+	stopIDs := make(map[string]bool, len(request.Stops))
+	for i, stop := range request.Stops {
+		key := fmt.Sprintf("stops[%d]", i+1)
+		switch {
+		case stop.ID == "":
+			validationErr[key] = fmt.Sprintf("stop number %d has no id", i+1)
+		case stopIDs[stop.ID]:
+			validationErr[key] = fmt.Sprintf("stop id %q is used more than once", stop.ID)
+		case stop.Demand < 0 || stop.ServiceTime < 0:
+			validationErr[key] = fmt.Sprintf("stop %q has a negative demand or service time", stop.ID)
+		}
+		stopIDs[stop.ID] = true
+		if _, err := service.ParseLocation(stop.Location.String(), order); err != nil {
+			validationErr[key] = fmt.Sprintf("stop %q has an invalid location: %s", stop.ID, err.Error())
+		}
+		if err := validateTimeWindow(stop.TimeWindow); err != nil {
+			validationErr[key] = fmt.Sprintf("stop %q has an invalid time window: %s", stop.ID, err.Error())
+		}
+	}
+
 	if len(validationErr) > 0 {
 		return nil, validationErr
 	}
 
 	return request, nil
 }
+
+func validateTimeWindow(w *TimeWindow) error {
+	if w == nil {
+		return nil
+	}
+	if w.Start < 0 || w.End < 0 {
+		return errors.New("times must not be negative")
+	}
+	if w.End != 0 && w.End < w.Start {
+		return errors.New("end is before start")
+	}
+	return nil
+}
+
+var pickupPointIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// reservedPickupPointIDs would be shadowed by the GET /pickup-points/... endpoints
+var reservedPickupPointIDs = map[string]bool{"nearest": true, "import": true, "export": true}
+
+// validatePickupPointRequest validates the body of POST and PUT /pickup-points, id is the ID in the path of PUT
+func validatePickupPointRequest(r *http.Request, id string) (*PickupPointRequest, ValidationError) {
+	validationErr := ValidationError{}
+
+	request := &PickupPointRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			validationErr["body"] = fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)
+		} else {
+			validationErr["body"] = fmt.Sprintf("invalid JSON body: %s", err.Error())
+		}
+		return nil, validationErr
+	}
+
+	if id != "" {
+		if request.ID != "" && request.ID != id {
+			validationErr["id"] = "id doesn't match the path"
+		}
+		request.ID = id
+	}
+	if request.ID != "" && !pickupPointIDPattern.MatchString(request.ID) {
+		validationErr["id"] = "id must be 1 to 64 letters, digits, dots, dashes or underscores"
+	}
+	if reservedPickupPointIDs[request.ID] {
+		validationErr["id"] = fmt.Sprintf("id %s is reserved", request.ID)
+	}
+
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+
+	if strings.TrimSpace(request.Name) == "" {
+		validationErr["name"] = "name is required"
+	}
+
+	if request.Location == "" {
+		validationErr["location"] = "location is required"
+	} else if _, err := service.ParseLocation(request.Location.String(), order); err != nil {
+		validationErr["location"] = err.Error()
+	}
+
+	if request.Capacity < 0 {
+		validationErr["capacity"] = "capacity must not be negative"
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+// validateImportPickupPointsRequest validates the parameters of POST /pickup-points/import, the file in the body
+// is validated row by row by the import. The format defaults to the one of the Content-Type header.
+func validateImportPickupPointsRequest(r *http.Request) (*ImportPickupPointsRequest, ValidationError) {
+	validationErr := ValidationError{}
+	params := r.URL.Query()
+
+	request := &ImportPickupPointsRequest{Format: params.Get("format")}
+	if request.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			request.Format = string(registry.FormatCSV)
+		case "application/geo+json", "application/json":
+			request.Format = string(registry.FormatGeoJSON)
+		}
+	}
+	if request.Format == "" {
+		validationErr["format"] = "format is required, as a parameter or a text/csv or application/geo+json Content-Type"
+	} else if err := registry.Format(request.Format).Validate(); err != nil {
+		validationErr["format"] = err.Error()
+	}
+
+	if dryRun := params.Get("dry_run"); dryRun != "" {
+		v, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			validationErr["dry_run"] = "dry run must be true or false"
+		}
+		request.DryRun = v
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+// validateExportPickupPointsRequest validates the parameters of GET /pickup-points/export, the format defaults to CSV
+func validateExportPickupPointsRequest(r *http.Request) (*ExportPickupPointsRequest, ValidationError) {
+	validationErr := ValidationError{}
+
+	request := &ExportPickupPointsRequest{Format: r.URL.Query().Get("format")}
+	if request.Format == "" {
+		request.Format = string(registry.FormatCSV)
+	}
+	if err := registry.Format(request.Format).Validate(); err != nil {
+		validationErr["format"] = err.Error()
+	}
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validateNearestPickupPointsRequest(r *http.Request) (*NearestPickupPointsRequest, ValidationError) {
+	validationErr := ValidationError{}
+	if len(r.URL.String()) > maxURLChars {
+		validationErr["url"] = fmt.Sprintf("URL is longer than %d characters", maxURLChars)
+	}
+
+	request := &NearestPickupPointsRequest{Count: defaultNearestCount}
+	params := r.URL.Query()
+
+	request.CoordOrder = params.Get("coord_order")
+	order := validateCoordOrder(request.CoordOrder, validationErr)
+
+	request.Source = Location(params.Get("src"))
+	if request.Source == "" {
+		validationErr["src"] = "source location is required"
+	} else if _, err := service.ParseLocation(request.Source.String(), order); err != nil {
+		validationErr["src"] = err.Error()
+	}
+
+	if n := params.Get("n"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 1 || v > maxNearestCount {
+			validationErr["n"] = fmt.Sprintf("n must be a number between 1 and %d", maxNearestCount)
+		} else {
+			request.Count = v
+		}
+	}
+
+	if radius := params.Get("radius_km"); radius != "" {
+		v, err := strconv.ParseFloat(radius, 64)
+		if err != nil || v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			validationErr["radius_km"] = "radius must be a positive number of kilometers"
+		} else {
+			request.Radius = v
+		}
+	}
+
+	request.Profile = params.Get("profile")
+	validateProfile(request.Profile, validationErr)
+
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+
+	return request, nil
+}
+
+func validateProfile(profile string, validationErr ValidationError) {
+	if profile == "" {
+		return
+	}
+	if err := service.Profile(profile).Validate(); err != nil {
+		validationErr["profile"] = err.Error()
+	}
+}
+
+// validateSnapOptions checks the snapping options shared by GET and POST /routes
+func validateSnapOptions(maxSnapDistance float64, snapPolicy string, validationErr ValidationError) {
+	if _, ok := validationErr["max_snap_distance"]; !ok && (maxSnapDistance < 0 || math.IsInf(maxSnapDistance, 0) || math.IsNaN(maxSnapDistance)) {
+		validationErr["max_snap_distance"] = "max snap distance must be a positive number of meters"
+	}
+
+	if snapPolicy != "" {
+		if err := service.SnapPolicy(snapPolicy).Validate(); err != nil {
+			validationErr["snap_policy"] = err.Error()
+		}
+	}
+}
+
+// validateFilterOptions checks the limit and max duration and distance shared by GET and POST /routes.
+// Fields that already failed to parse are not checked again.
+func validateFilterOptions(limit int, maxDuration, maxDistance float64, validationErr ValidationError) {
+	if _, ok := validationErr["limit"]; !ok && limit < 0 {
+		validationErr["limit"] = "limit must be a positive number of routes"
+	}
+	if _, ok := validationErr["max_duration"]; !ok && (maxDuration < 0 || math.IsInf(maxDuration, 0) || math.IsNaN(maxDuration)) {
+		validationErr["max_duration"] = "max duration must be a positive number of seconds"
+	}
+	if _, ok := validationErr["max_distance"]; !ok && (maxDistance < 0 || math.IsInf(maxDistance, 0) || math.IsNaN(maxDistance)) {
+		validationErr["max_distance"] = "max distance must be a positive number of meters"
+	}
+}
+
+// validateRankingOptions checks the ranking strategy and its parameters shared by GET and POST /routes.
+// Fields that already failed to parse are not checked again.
+func validateRankingOptions(ranking string, durationWeight, distanceWeight, tolerance float64, validationErr ValidationError) {
+	if ranking != "" {
+		if err := service.RankingStrategy(ranking).Validate(); err != nil {
+			validationErr["ranking"] = err.Error()
+		}
+	}
+
+	invalid := func(v float64) bool { return v < 0 || math.IsInf(v, 0) || math.IsNaN(v) }
+	if _, ok := validationErr["duration_weight"]; !ok && invalid(durationWeight) {
+		validationErr["duration_weight"] = "duration weight must be a positive number"
+	}
+	if _, ok := validationErr["distance_weight"]; !ok && invalid(distanceWeight) {
+		validationErr["distance_weight"] = "distance weight must be a positive number"
+	}
+	if _, ok := validationErr["tolerance"]; !ok && invalid(tolerance) {
+		validationErr["tolerance"] = "tolerance must be a positive number of seconds"
+	}
+
+	switch service.RankingStrategy(ranking) {
+	case service.RankingWeighted:
+		if durationWeight == 0 && distanceWeight == 0 {
+			validationErr["ranking"] = "weighted ranking needs a duration or distance weight"
+		}
+	case service.RankingTolerance:
+		if tolerance == 0 {
+			validationErr["tolerance"] = "tolerance ranking needs a tolerance in seconds"
+		}
+	}
+}
+
+// floatParam parses the optional number query parameter, 0 if absent or invalid
+func floatParam(params url.Values, key string, validationErr ValidationError) float64 {
+	param := params.Get(key)
+	if param == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		validationErr[key] = fmt.Sprintf("%s must be a number", strings.ReplaceAll(key, "_", " "))
+		return 0
+	}
+	return v
+}
+
+// validateCoordOrder returns the order locations are given in, latitude first unless coordOrder says otherwise.
+// The default order is returned for invalid values so that locations can still be validated.
+func validateCoordOrder(coordOrder string, validationErr ValidationError) service.CoordOrder {
+	if coordOrder == "" {
+		return service.CoordOrderLatLon
+	}
+
+	order := service.CoordOrder(coordOrder)
+	if err := order.Validate(); err != nil {
+		validationErr["coord_order"] = err.Error()
+		return service.CoordOrderLatLon
+	}
+
+	return order
+}
+
+// isJSONObject tells whether the raw JSON is an object, or empty or null
+func isJSONObject(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null")) || raw[0] == '{'
+}
@@ -1,8 +1,12 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -54,6 +58,52 @@ func TestValidateGetRoutesRequest(t *testing.T) {
 		wantErrFields   []string
 		validateRequest func(*testing.T, *GetRoutesRequest)
 	}{
+		{
+			name: "valid request with destination refs",
+			queryParams: map[string][]string{
+				"src":     {"12.3456,78.9101"},
+				"dst":     {"13.1234,79.9101", "14.5678,80.1234"},
+				"dst_ref": {"order-1", "order-2"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, []string{"order-1", "order-2"}, req.DestinationRefs)
+			},
+		},
+		{
+			name: "fewer destination refs than destinations",
+			queryParams: map[string][]string{
+				"src":     {"12.3456,78.9101"},
+				"dst":     {"13.1234,79.9101", "14.5678,80.1234"},
+				"dst_ref": {"order-1"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"dst_ref"},
+		},
+		{
+			name: "valid request with pickup point ids",
+			queryParams: map[string][]string{
+				"src":    {"12.3456,78.9101"},
+				"dst":    {"13.1234,79.9101"},
+				"dst_id": {"locker-1", "locker-2"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Len(t, req.Destinations, 1)
+				assert.Equal(t, []string{"locker-1", "locker-2"}, req.DestinationIDs)
+			},
+		},
+		{
+			name: "empty pickup point id",
+			queryParams: map[string][]string{
+				"src":    {"12.3456,78.9101"},
+				"dst_id": {""},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"dst_id[1]"},
+		},
 		{
 			name: "valid request with single destination",
 			queryParams: map[string][]string{
@@ -308,6 +358,173 @@ func TestValidateGetRoutesRequest(t *testing.T) {
 				assert.Equal(t, Location("-13.1234,-79.9101"), req.Destinations[0])
 			},
 		},
+		{
+			name: "valid snap options",
+			queryParams: map[string][]string{
+				"src":               {"12.3456,78.9101"},
+				"dst":               {"13.1234,79.9101"},
+				"max_snap_distance": {"250.5"},
+				"snap_policy":       {"exclude"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, 250.5, req.MaxSnapDistance)
+				assert.Equal(t, "exclude", req.SnapPolicy)
+			},
+		},
+		{
+			name: "longitude first with coord_order=lonlat",
+			queryParams: map[string][]string{
+				"src":         {"-122.4194,37.7749"},
+				"dst":         {"-122.2711,37.8044"},
+				"coord_order": {"lonlat"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, "lonlat", req.CoordOrder)
+			},
+		},
+		{
+			name: "longitude first without coord_order",
+			queryParams: map[string][]string{
+				"src": {"-122.4194,37.7749"},
+				"dst": {"-122.2711,37.8044"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"src", "dst[1]"},
+		},
+		{
+			name: "unknown coord_order",
+			queryParams: map[string][]string{
+				"src":         {"12.3456,78.9101"},
+				"dst":         {"13.1234,79.9101"},
+				"coord_order": {"xy"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"coord_order"},
+		},
+		{
+			name: "walking profile",
+			queryParams: map[string][]string{
+				"src":     {"12.3456,78.9101"},
+				"dst":     {"13.1234,79.9101"},
+				"profile": {"walking"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, "walking", req.Profile)
+			},
+		},
+		{
+			name: "unknown profile",
+			queryParams: map[string][]string{
+				"src":     {"12.3456,78.9101"},
+				"dst":     {"13.1234,79.9101"},
+				"profile": {"flying"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"profile"},
+		},
+		{
+			name: "invalid max snap distance",
+			queryParams: map[string][]string{
+				"src":               {"12.3456,78.9101"},
+				"dst":               {"13.1234,79.9101"},
+				"max_snap_distance": {"far"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"max_snap_distance"},
+		},
+		{
+			name: "negative max snap distance",
+			queryParams: map[string][]string{
+				"src":               {"12.3456,78.9101"},
+				"dst":               {"13.1234,79.9101"},
+				"max_snap_distance": {"-1"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"max_snap_distance"},
+		},
+		{
+			name: "unknown snap policy",
+			queryParams: map[string][]string{
+				"src":         {"12.3456,78.9101"},
+				"dst":         {"13.1234,79.9101"},
+				"snap_policy": {"drop"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"snap_policy"},
+		},
+		{
+			name: "valid filters",
+			queryParams: map[string][]string{
+				"src":          {"12.3456,78.9101"},
+				"dst":          {"13.1234,79.9101"},
+				"limit":        {"3"},
+				"max_duration": {"900"},
+				"max_distance": {"5000.5"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, 3, req.Limit)
+				assert.Equal(t, 900.0, req.MaxDuration)
+				assert.Equal(t, 5000.5, req.MaxDistance)
+			},
+		},
+		{
+			name: "valid weighted ranking",
+			queryParams: map[string][]string{
+				"src":             {"12.3456,78.9101"},
+				"dst":             {"13.1234,79.9101"},
+				"ranking":         {"weighted"},
+				"duration_weight": {"1"},
+				"distance_weight": {"0.05"},
+			},
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *GetRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, "weighted", req.Ranking)
+				assert.Equal(t, 1.0, req.DurationWeight)
+				assert.Equal(t, 0.05, req.DistanceWeight)
+			},
+		},
+		{
+			name: "weighted ranking without weights",
+			queryParams: map[string][]string{
+				"src":     {"12.3456,78.9101"},
+				"dst":     {"13.1234,79.9101"},
+				"ranking": {"weighted"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"ranking"},
+		},
+		{
+			name: "unknown ranking and invalid tolerance",
+			queryParams: map[string][]string{
+				"src":       {"12.3456,78.9101"},
+				"dst":       {"13.1234,79.9101"},
+				"ranking":   {"cheapest"},
+				"tolerance": {"soon"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"ranking", "tolerance"},
+		},
+		{
+			name: "invalid filters",
+			queryParams: map[string][]string{
+				"src":          {"12.3456,78.9101"},
+				"dst":          {"13.1234,79.9101"},
+				"limit":        {"1.5"},
+				"max_duration": {"-1"},
+				"max_distance": {"far"},
+			},
+			wantErr:       true,
+			wantErrFields: []string{"limit", "max_duration", "max_distance"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -341,6 +558,571 @@ func TestValidateGetRoutesRequest(t *testing.T) {
 	}
 }
 
+func TestValidatePostRoutesRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		wantErr         bool
+		wantErrFields   []string
+		validateRequest func(*testing.T, *PostRoutesRequest)
+	}{
+		{
+			name:    "valid request with multiple destinations",
+			body:    `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101","14.5678,80.1234"]}`,
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *PostRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, Location("12.3456,78.9101"), req.Source)
+				assert.Equal(t, []RouteDestination{{Location: "13.1234,79.9101"}, {Location: "14.5678,80.1234"}}, req.Destinations)
+			},
+		},
+		{
+			name:    "more destinations than allowed in GET",
+			body:    buildPostBody("12.3456,78.9101", maxDstGET+1),
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *PostRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Len(t, req.Destinations, maxDstGET+1)
+			},
+		},
+		{
+			name:          "invalid JSON",
+			body:          `{"source":`,
+			wantErr:       true,
+			wantErrFields: []string{"body"},
+		},
+		{
+			name:          "missing source and destinations",
+			body:          `{}`,
+			wantErr:       true,
+			wantErrFields: []string{"source", "destinations"},
+		},
+		{
+			name:          "invalid source",
+			body:          `{"source":"invalid","destinations":["13.1234,79.9101"]}`,
+			wantErr:       true,
+			wantErrFields: []string{"source"},
+		},
+		{
+			name:          "invalid destination",
+			body:          `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101","13.1234,180.0"]}`,
+			wantErr:       true,
+			wantErrFields: []string{"destinations[2]"},
+		},
+		{
+			name:    "destinations with id and metadata",
+			body:    `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101",{"location":"14.5678,80.1234","id":"order-7","metadata":{"slot":3}}]}`,
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *PostRoutesRequest) {
+				require.NotNil(t, req)
+				require.Len(t, req.Destinations, 2)
+				assert.Equal(t, RouteDestination{Location: "13.1234,79.9101"}, req.Destinations[0])
+				assert.Equal(t, Location("14.5678,80.1234"), req.Destinations[1].Location)
+				assert.Equal(t, "order-7", req.Destinations[1].ID)
+				assert.JSONEq(t, `{"slot":3}`, string(req.Destinations[1].Metadata))
+			},
+		},
+		{
+			name:          "metadata that is not an object",
+			body:          `{"source":"12.3456,78.9101","destinations":[{"location":"13.1234,79.9101","metadata":[1,2]}]}`,
+			wantErr:       true,
+			wantErrFields: []string{"destinations[1]"},
+		},
+		{
+			name:          "destination object without location",
+			body:          `{"source":"12.3456,78.9101","destinations":[{"id":"order-7"}]}`,
+			wantErr:       true,
+			wantErrFields: []string{"destinations[1]"},
+		},
+		{
+			name:    "valid snap options",
+			body:    `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101"],"max_snap_distance":100,"snap_policy":"flag"}`,
+			wantErr: false,
+			validateRequest: func(t *testing.T, req *PostRoutesRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, 100.0, req.MaxSnapDistance)
+				assert.Equal(t, "flag", req.SnapPolicy)
+			},
+		},
+		{
+			name:    "longitude first with coord_order=lonlat",
+			body:    `{"source":"-122.4194,37.7749","destinations":["-122.2711,37.8044"],"coord_order":"lonlat"}`,
+			wantErr: false,
+		},
+		{
+			name:          "unknown profile",
+			body:          `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101"],"profile":"flying"}`,
+			wantErr:       true,
+			wantErrFields: []string{"profile"},
+		},
+		{
+			name:          "invalid snap options",
+			body:          `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101"],"max_snap_distance":-5,"snap_policy":"drop"}`,
+			wantErr:       true,
+			wantErrFields: []string{"max_snap_distance", "snap_policy"},
+		},
+		{
+			name:          "tolerance ranking without tolerance and negative weight",
+			body:          `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101"],"ranking":"tolerance","distance_weight":-1}`,
+			wantErr:       true,
+			wantErrFields: []string{"tolerance", "distance_weight"},
+		},
+		{
+			name:          "invalid filters",
+			body:          `{"source":"12.3456,78.9101","destinations":["13.1234,79.9101"],"limit":-1,"max_duration":-60,"max_distance":-1000}`,
+			wantErr:       true,
+			wantErrFields: []string{"limit", "max_duration", "max_distance"},
+		},
+		{
+			name:          "too many destinations",
+			body:          buildPostBody("12.3456,78.9101", maxDstPOST+1),
+			wantErr:       true,
+			wantErrFields: []string{"destinations"},
+		},
+		{
+			name:          "body too large",
+			body:          `{"source":"12.3456,78.9101","destinations":["` + strings.Repeat("1", maxBodyBytes) + `"]}`,
+			wantErr:       true,
+			wantErrFields: []string{"body"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/routes", strings.NewReader(tt.body))
+			req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, maxBodyBytes)
+
+			request, validationErr := validatePostRoutesRequest(req)
+
+			if tt.wantErr {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+				if tt.validateRequest != nil {
+					tt.validateRequest(t, request)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePostMatrixRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantErr       bool
+		wantErrFields []string
+	}{
+		{
+			name:    "valid request",
+			body:    `{"sources":["12.3456,78.9101","12.5,78.5"],"destinations":["13.1234,79.9101"],"profile":"cycling"}`,
+			wantErr: false,
+		},
+		{
+			name:          "missing sources and destinations",
+			body:          `{}`,
+			wantErr:       true,
+			wantErrFields: []string{"sources", "destinations"},
+		},
+		{
+			name:          "invalid source and destination",
+			body:          `{"sources":["12.3456,78.9101","invalid"],"destinations":["13.1234,180.0"]}`,
+			wantErr:       true,
+			wantErrFields: []string{"sources[2]", "destinations[1]"},
+		},
+		{
+			name:          "too many cells",
+			body:          `{"sources":[` + strings.TrimSuffix(strings.Repeat(`"12.3456,78.9101",`, maxSrcMatrix), ",") + `],"destinations":[` + strings.TrimSuffix(strings.Repeat(`"13.1234,79.9101",`, maxDstMatrix), ",") + `]}`,
+			wantErr:       true,
+			wantErrFields: []string{"matrix"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/matrix", strings.NewReader(tt.body))
+
+			request, validationErr := validatePostMatrixRequest(req)
+
+			if tt.wantErr {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+			}
+		})
+	}
+}
+
+func TestValidateGetRouteRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        map[string][]string
+		wantErr       bool
+		wantErrFields []string
+	}{
+		{
+			name:    "valid request",
+			params:  map[string][]string{"src": {"52.517037,13.388860"}, "dst": {"52.529407,13.397634"}, "geometry": {"geojson"}, "steps": {"true"}},
+			wantErr: false,
+		},
+		{
+			name:          "missing source and destination",
+			params:        map[string][]string{},
+			wantErr:       true,
+			wantErrFields: []string{"src", "dst"},
+		},
+		{
+			name:          "more than one destination",
+			params:        map[string][]string{"src": {"52.517037,13.388860"}, "dst": {"52.529407,13.397634", "52.523219,13.428555"}},
+			wantErr:       true,
+			wantErrFields: []string{"dst"},
+		},
+		{
+			name:          "invalid geometry and steps",
+			params:        map[string][]string{"src": {"52.517037,13.388860"}, "dst": {"52.529407,13.397634"}, "geometry": {"wkt"}, "steps": {"sometimes"}},
+			wantErr:       true,
+			wantErrFields: []string{"geometry", "steps"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/route?"+buildQuery(tt.params), nil)
+
+			request, validationErr := validateGetRouteRequest(req)
+
+			if tt.wantErr {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+			}
+		})
+	}
+}
+
+func TestValidatePostTripsRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantErr       bool
+		wantErrFields []string
+	}{
+		{
+			name:    "valid round trip",
+			body:    `{"source":"52.517037,13.388860","stops":["52.529407,13.397634","52.523219,13.428555"]}`,
+			wantErr: false,
+		},
+		{
+			name:    "valid trip with a fixed end",
+			body:    `{"source":"52.517037,13.388860","stops":["52.529407,13.397634"],"end":"52.520008,13.404954","roundtrip":false}`,
+			wantErr: false,
+		},
+		{
+			name:          "missing source and stops",
+			body:          `{}`,
+			wantErr:       true,
+			wantErrFields: []string{"source", "stops"},
+		},
+		{
+			name:          "invalid stop and end",
+			body:          `{"source":"52.517037,13.388860","stops":["52.529407,13.397634","invalid"],"end":"91,13.4"}`,
+			wantErr:       true,
+			wantErrFields: []string{"stops[2]", "end"},
+		},
+		{
+			name:          "round trip with a fixed end",
+			body:          `{"source":"52.517037,13.388860","stops":["52.529407,13.397634"],"end":"52.520008,13.404954","roundtrip":true}`,
+			wantErr:       true,
+			wantErrFields: []string{"roundtrip"},
+		},
+		{
+			name:          "too many stops",
+			body:          `{"source":"52.517037,13.388860","stops":[` + strings.TrimSuffix(strings.Repeat(`"52.529407,13.397634",`, maxStopsTrip+1), ",") + `]}`,
+			wantErr:       true,
+			wantErrFields: []string{"stops"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/trips", strings.NewReader(tt.body))
+
+			request, validationErr := validatePostTripsRequest(req)
+
+			if tt.wantErr {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+			}
+		})
+	}
+}
+
+func TestValidatePostPlansRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantErr       bool
+		wantErrFields []string
+	}{
+		{
+			name:    "valid request",
+			body:    `{"vehicles":[{"id":"van-1","start":"52.517037,13.388860","capacity":10,"shift":{"start":28800,"end":61200}}],"stops":[{"id":"a","location":"52.529407,13.397634","demand":2,"time_window":{"start":32400,"end":36000},"service_time":300}],"seed":7}`,
+			wantErr: false,
+		},
+		{
+			name:          "missing vehicles and stops",
+			body:          `{}`,
+			wantErr:       true,
+			wantErrFields: []string{"vehicles", "stops"},
+		},
+		{
+			name:          "duplicate ids",
+			body:          `{"vehicles":[{"id":"van","start":"52.517037,13.388860"},{"id":"van","start":"52.517037,13.388860"}],"stops":[{"id":"a","location":"52.529407,13.397634"},{"id":"a","location":"52.529407,13.397634"}]}`,
+			wantErr:       true,
+			wantErrFields: []string{"vehicles[2]", "stops[2]"},
+		},
+		{
+			name:          "invalid location and time window",
+			body:          `{"vehicles":[{"id":"van","start":"52.517037,13.388860","end":"invalid"}],"stops":[{"id":"a","location":"52.529407,13.397634","time_window":{"start":36000,"end":32400}}]}`,
+			wantErr:       true,
+			wantErrFields: []string{"vehicles[1]", "stops[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/plans", strings.NewReader(tt.body))
+
+			request, validationErr := validatePostPlansRequest(req)
+
+			if tt.wantErr {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+			}
+		})
+	}
+}
+
+func TestValidatePickupPointRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		pathID        string
+		wantErr       bool
+		wantErrFields []string
+	}{
+		{
+			name:    "valid request",
+			body:    `{"id":"locker-1","name":"Locker","location":"52.529407,13.397634","carrier":"DHL","opening_hours":"Mo-Su 00:00-24:00","capacity":40}`,
+			wantErr: false,
+		},
+		{
+			name:    "valid update with the id in the path",
+			body:    `{"name":"Locker","location":"13.397634,52.529407","coord_order":"lonlat"}`,
+			pathID:  "locker-1",
+			wantErr: false,
+		},
+		{
+			name:          "missing name and location",
+			body:          `{}`,
+			wantErr:       true,
+			wantErrFields: []string{"name", "location"},
+		},
+		{
+			name:          "invalid id, location and capacity",
+			body:          `{"id":"locker 1","name":"Locker","location":"91,13.4","capacity":-1}`,
+			wantErr:       true,
+			wantErrFields: []string{"id", "location", "capacity"},
+		},
+		{
+			name:          "reserved id",
+			body:          `{"id":"export","name":"Locker","location":"52.529407,13.397634"}`,
+			wantErr:       true,
+			wantErrFields: []string{"id"},
+		},
+		{
+			name:          "id not matching the path",
+			body:          `{"id":"locker-2","name":"Locker","location":"52.529407,13.397634"}`,
+			pathID:        "locker-1",
+			wantErr:       true,
+			wantErrFields: []string{"id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/pickup-points", strings.NewReader(tt.body))
+
+			request, validationErr := validatePickupPointRequest(req, tt.pathID)
+
+			if tt.wantErr {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+			}
+		})
+	}
+}
+
+func TestValidateImportPickupPointsRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		contentType   string
+		wantFormat    string
+		wantDryRun    bool
+		wantErrFields []string
+	}{
+		{
+			name:       "format parameter",
+			query:      "format=geojson&dry_run=true",
+			wantFormat: "geojson",
+			wantDryRun: true,
+		},
+		{
+			name:        "format from the content type",
+			contentType: "text/csv; charset=utf-8",
+			wantFormat:  "csv",
+		},
+		{
+			name:          "missing format",
+			contentType:   "text/plain",
+			wantErrFields: []string{"format"},
+		},
+		{
+			name:          "invalid format and dry run",
+			query:         "format=xlsx&dry_run=maybe",
+			wantErrFields: []string{"format", "dry_run"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/pickup-points/import?"+tt.query, strings.NewReader(""))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			request, validationErr := validateImportPickupPointsRequest(req)
+
+			if len(tt.wantErrFields) > 0 {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+				assert.Equal(t, tt.wantFormat, request.Format)
+				assert.Equal(t, tt.wantDryRun, request.DryRun)
+			}
+		})
+	}
+}
+
+func TestValidateNearestPickupPointsRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		wantCount     int
+		wantRadius    float64
+		wantErrFields []string
+	}{
+		{
+			name:      "defaults",
+			query:     "src=52.517037,13.388860",
+			wantCount: 5,
+		},
+		{
+			name:       "count and radius",
+			query:      "src=13.388860,52.517037&coord_order=lonlat&n=10&radius_km=2.5&profile=cycling",
+			wantCount:  10,
+			wantRadius: 2.5,
+		},
+		{
+			name:          "missing source",
+			query:         "n=3",
+			wantErrFields: []string{"src"},
+		},
+		{
+			name:          "count out of range and negative radius",
+			query:         "src=52.517037,13.388860&n=51&radius_km=-1",
+			wantErrFields: []string{"n", "radius_km"},
+		},
+		{
+			name:          "invalid count and profile",
+			query:         "src=52.517037,13.388860&n=five&profile=flying",
+			wantErrFields: []string{"n", "profile"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/pickup-points/nearest?"+tt.query, nil)
+
+			request, validationErr := validateNearestPickupPointsRequest(req)
+
+			if len(tt.wantErrFields) > 0 {
+				require.NotNil(t, validationErr, "expected validation error but got none")
+				for _, field := range tt.wantErrFields {
+					assert.Contains(t, validationErr, field, "validation error should contain field: %s", field)
+				}
+				assert.Nil(t, request, "request should be nil when validation fails")
+			} else {
+				assert.Nil(t, validationErr, "expected no validation error but got: %v", validationErr)
+				require.NotNil(t, request, "request should not be nil when validation succeeds")
+				assert.Equal(t, tt.wantCount, request.Count)
+				assert.Equal(t, tt.wantRadius, request.Radius)
+			}
+		})
+	}
+}
+
+func buildPostBody(source string, count int) string {
+	body := PostRoutesRequest{
+		Source:       Location(source),
+		Destinations: make([]RouteDestination, count),
+	}
+	for i := range body.Destinations {
+		body.Destinations[i] = RouteDestination{Location: "13.1234,79.9101"}
+	}
+
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(body)
+	return buf.String()
+}
+
 func buildQuery(params map[string][]string) string {
 	if len(params) == 0 {
 		return ""
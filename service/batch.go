@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchWindow          = 5 * time.Millisecond
+	defaultBatchMaxDestinations = 500
+)
+
+type BatchConfig struct {
+	// Window is how long a batch collects requests for the same source before it's sent
+	Window time.Duration
+	// MaxDestinations caps the number of distinct destinations merged into one upstream call.
+	// A batch is sent as soon as it's full, and requests at least this large bypass batching.
+	MaxDestinations int
+}
+
+// BatchingRouteFinder merges requests that share a profile and source and arrive within a short window
+// into one upstream call, then hands each caller the routes for its own destinations.
+type BatchingRouteFinder struct {
+	routeFinder
+	window          time.Duration
+	maxDestinations int
+
+	mu      sync.Mutex
+	pending map[batchKey]*batch
+}
+
+type batchKey struct {
+	profile Profile
+	source  Location
+}
+
+type batch struct {
+	key          batchKey
+	destinations []Location
+	positions    map[Location]int
+	timer        *time.Timer
+	ctx          context.Context
+	cancel       context.CancelFunc
+	waiters      int
+	callers      int
+	sent         bool
+	done         chan struct{}
+	routes       []*Route
+	err          error
+}
+
+func NewBatchingRouteFinder(routeFinder routeFinder, cfg *BatchConfig) *BatchingRouteFinder {
+	if cfg == nil {
+		cfg = &BatchConfig{}
+	}
+
+	window := defaultBatchWindow
+	if cfg.Window > 0 {
+		window = cfg.Window
+	}
+
+	maxDestinations := defaultBatchMaxDestinations
+	if cfg.MaxDestinations > 0 {
+		maxDestinations = cfg.MaxDestinations
+	}
+
+	return &BatchingRouteFinder{
+		routeFinder:     routeFinder,
+		window:          window,
+		maxDestinations: maxDestinations,
+		pending:         make(map[batchKey]*batch),
+	}
+}
+
+func (c *BatchingRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	if len(destinations) >= c.maxDestinations {
+		return c.routeFinder.FindFastestRoutes(ctx, profile, source, destinations)
+	}
+
+	b, positions := c.join(ctx, batchKey{profile: profile, source: source}, destinations)
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		c.leave(b)
+		return nil, ctx.Err()
+	}
+
+	if b.err != nil {
+		// One caller's destination (e.g. one that can't be snapped) must not fail everybody else's
+		if b.callers > 1 && !failsEveryCaller(b.err) {
+			return c.routeFinder.FindFastestRoutes(ctx, profile, source, destinations)
+		}
+		return nil, b.err
+	}
+
+	routes := make([]*Route, len(destinations))
+	for i, pos := range positions {
+		route := *b.routes[pos]
+		route.Destination = destinations[i]
+		routes[i] = &route
+	}
+
+	return routes, nil
+}
+
+// join adds the destinations to the pending batch of the profile and source, opening a new batch if needed,
+// and returns the position of each destination within the batch
+func (c *BatchingRouteFinder) join(ctx context.Context, key batchKey, destinations []Location) (*batch, []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.pending[key]
+	if b != nil && len(b.destinations)+countNew(b, destinations) > c.maxDestinations {
+		c.send(b)
+		b = nil
+	}
+
+	if b == nil {
+		// The batch keeps the values of the first caller's context but not its cancellation
+		batchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		b = &batch{
+			key:       key,
+			positions: make(map[Location]int),
+			ctx:       batchCtx,
+			cancel:    cancel,
+			done:      make(chan struct{}),
+		}
+		b.timer = time.AfterFunc(c.window, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.send(b)
+		})
+		c.pending[key] = b
+	}
+
+	positions := make([]int, len(destinations))
+	for i, d := range destinations {
+		pos, ok := b.positions[d]
+		if !ok {
+			pos = len(b.destinations)
+			b.positions[d] = pos
+			b.destinations = append(b.destinations, d)
+		}
+		positions[i] = pos
+	}
+	b.waiters++
+	b.callers++
+
+	if len(b.destinations) >= c.maxDestinations {
+		c.send(b)
+	}
+
+	return b, positions
+}
+
+// send closes the batch to new callers and starts the upstream call. Must be called with c.mu held.
+func (c *BatchingRouteFinder) send(b *batch) {
+	if b.sent {
+		return
+	}
+	b.sent = true
+	b.timer.Stop()
+	if c.pending[b.key] == b {
+		delete(c.pending, b.key)
+	}
+
+	if b.waiters == 0 {
+		b.cancel()
+		return
+	}
+
+	batchDestinations.Observe(float64(len(b.destinations)))
+	batchCallers.Observe(float64(b.callers))
+
+	go func() {
+		defer b.cancel()
+		b.routes, b.err = c.routeFinder.FindFastestRoutes(b.ctx, b.key.profile, b.key.source, b.destinations)
+		if b.err == nil {
+			b.err = checkRoutes(b.routes, len(b.destinations))
+		}
+		close(b.done)
+	}()
+}
+
+// leave cancels the batch when the last caller waiting on it is gone
+func (c *BatchingRouteFinder) leave(b *batch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b.waiters--
+	if b.waiters > 0 {
+		return
+	}
+
+	b.cancel()
+	if c.pending[b.key] == b {
+		delete(c.pending, b.key)
+	}
+}
+
+// failsEveryCaller tells whether the error of a batch would fail each of its callers on its own too,
+// e.g. while the routing engine is down, so that callers don't multiply the load by retrying one by one
+func failsEveryCaller(err error) bool {
+	return errors.Is(err, ErrUnavailable) ||
+		errors.Is(err, errIncompleteRoutes) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+func countNew(b *batch, destinations []Location) int {
+	seen := make(map[Location]struct{})
+	for _, d := range destinations {
+		if _, ok := b.positions[d]; ok {
+			continue
+		}
+		seen[d] = struct{}{}
+	}
+	return len(seen)
+}
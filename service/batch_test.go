@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchingRouteFinder_MergesRequestsFromSameSource(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	batching := NewBatchingRouteFinder(finder, &BatchConfig{Window: 50 * time.Millisecond})
+
+	requests := [][]Location{
+		{"52.51,13.41", "52.52,13.42"},
+		{"52.52,13.42", "52.53,13.43"},
+		{"52.54,13.44"},
+	}
+	results := make([][]*Route, len(requests))
+	var wg sync.WaitGroup
+	for i, destinations := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			routes, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", destinations)
+			assert.NoError(t, err)
+			results[i] = routes
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, finder.calls, 1)
+	assert.Len(t, finder.calls[0], 4)
+	for i, destinations := range requests {
+		require.Len(t, results[i], len(destinations))
+		for j, d := range destinations {
+			assert.Equal(t, d, results[i][j].Destination)
+		}
+	}
+	assert.Equal(t, results[0][1].Duration, results[1][0].Duration)
+}
+
+func TestBatchingRouteFinder_SeparatesSources(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	batching := NewBatchingRouteFinder(finder, &BatchConfig{Window: 20 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	for _, source := range []Location{"52.5,13.4", "48.1,11.5"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, source, []Location{"52.51,13.41"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, finder.calls, 2)
+}
+
+func TestBatchingRouteFinder_SendsFullBatchImmediately(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	batching := NewBatchingRouteFinder(finder, &BatchConfig{Window: time.Hour, MaxDestinations: 2})
+
+	var wg sync.WaitGroup
+	for _, d := range []Location{"52.51,13.41", "52.52,13.42"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", []Location{d})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, finder.calls, 1)
+	assert.Len(t, finder.calls[0], 2)
+
+	_, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", []Location{"52.51,13.41", "52.52,13.42"})
+	require.NoError(t, err)
+	assert.Len(t, finder.calls, 2)
+}
+
+func TestBatchingRouteFinder_CallerCancellation(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	batching := NewBatchingRouteFinder(finder, &BatchConfig{Window: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := batching.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, finder.calls)
+}
+
+func TestBatchingRouteFinder_ReturnsUpstreamError(t *testing.T) {
+	finder := &fakeRouteFinder{err: errors.New("osrm down")}
+	batching := NewBatchingRouteFinder(finder, &BatchConfig{Window: time.Millisecond})
+
+	_, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+
+	assert.EqualError(t, err, "osrm down")
+}
+
+func TestBatchingRouteFinder_RetriesPerCallerOnlyForDestinationErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "destination error", err: errors.New("NoSegment: could not find a matching segment"), wantCalls: 3},
+		{name: "unavailable", err: fmt.Errorf("%w: connection refused", ErrUnavailable), wantCalls: 1},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeRouteFinder{err: tt.err}
+			batching := NewBatchingRouteFinder(finder, &BatchConfig{Window: 50 * time.Millisecond})
+
+			var wg sync.WaitGroup
+			for _, destinations := range [][]Location{{"52.51,13.41"}, {"52.52,13.42"}} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", destinations)
+					assert.ErrorIs(t, err, tt.err)
+				}()
+			}
+			wg.Wait()
+
+			assert.Len(t, finder.calls, tt.wantCalls)
+		})
+	}
+}
+
+func TestBatchingRouteFinder_IncompleteRoutes(t *testing.T) {
+	batching := NewBatchingRouteFinder(&staticRouteFinder{routes: []*Route{{Destination: "52.51,13.41"}, nil}}, &BatchConfig{Window: time.Millisecond})
+
+	_, err := batching.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", []Location{"52.51,13.41", "52.52,13.42"})
+
+	assert.ErrorIs(t, err, errIncompleteRoutes)
+}
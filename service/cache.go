@@ -0,0 +1,234 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultCacheTTL       = 15 * time.Minute
+	defaultCachePrecision = 5 // ~1.1m at the equator
+	defaultCacheMaxBytes  = 64 << 20
+	// cacheEntryOverhead approximates the memory used by one entry besides its key
+	// (list element, map bucket slot, cached route)
+	cacheEntryOverhead = 160
+)
+
+type CacheConfig struct {
+	// TTL is how long a cached route stays valid
+	TTL time.Duration
+	// Precision is the number of decimal places coordinates are rounded to when building cache keys
+	Precision int
+	// MaxBytes bounds the approximate memory used by the cache; least recently used entries are evicted first
+	MaxBytes int64
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+// CachingRouteFinder caches routes per profile and source→destination pair in front of another routeFinder,
+// and only asks it for the pairs that are missing or expired.
+type CachingRouteFinder struct {
+	routeFinder
+	ttl       time.Duration
+	precision int
+	maxBytes  int64
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type cacheEntry struct {
+	key       string
+	route     Route
+	expiresAt time.Time
+}
+
+func NewCachingRouteFinder(routeFinder routeFinder, cfg *CacheConfig) *CachingRouteFinder {
+	if cfg == nil {
+		cfg = &CacheConfig{}
+	}
+
+	ttl := defaultCacheTTL
+	if cfg.TTL > 0 {
+		ttl = cfg.TTL
+	}
+
+	precision := defaultCachePrecision
+	if cfg.Precision > 0 {
+		precision = cfg.Precision
+	}
+
+	maxBytes := int64(defaultCacheMaxBytes)
+	if cfg.MaxBytes > 0 {
+		maxBytes = cfg.MaxBytes
+	}
+
+	return &CachingRouteFinder{
+		routeFinder: routeFinder,
+		ttl:         ttl,
+		precision:   precision,
+		maxBytes:    maxBytes,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+func (c *CachingRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	routes := make([]*Route, len(destinations))
+	sourceKey := string(profile) + "|" + c.quantize(source)
+
+	// Destinations that quantize to the same key are only requested once
+	missing := make([]Location, 0)
+	missingKeys := make([]string, 0)
+	missingIndices := make(map[string][]int)
+	for i, d := range destinations {
+		key := sourceKey + "|" + c.quantize(d)
+		if route, ok := c.get(key); ok {
+			c.hits.Add(1)
+			cacheLookupsTotal.WithLabelValues("hit").Inc()
+			route.Destination = d
+			routes[i] = &route
+			continue
+		}
+
+		c.misses.Add(1)
+		cacheLookupsTotal.WithLabelValues("miss").Inc()
+		if _, ok := missingIndices[key]; !ok {
+			missing = append(missing, d)
+			missingKeys = append(missingKeys, key)
+		}
+		missingIndices[key] = append(missingIndices[key], i)
+	}
+
+	if len(missing) == 0 {
+		return routes, nil
+	}
+
+	found, err := c.routeFinder.FindFastestRoutes(ctx, profile, source, missing)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRoutes(found, len(missing)); err != nil {
+		return nil, err
+	}
+
+	for i, key := range missingKeys {
+		c.set(key, *found[i])
+		for _, idx := range missingIndices[key] {
+			route := *found[i]
+			route.Destination = destinations[idx]
+			routes[idx] = &route
+		}
+	}
+
+	return routes, nil
+}
+
+// Stats returns the cache counters, useful to tune TTL, precision and size
+func (c *CachingRouteFinder) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
+	}
+}
+
+func (c *CachingRouteFinder) get(key string) (Route, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return Route{}, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.remove(elem)
+		return Route{}, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.route, true
+}
+
+func (c *CachingRouteFinder) set(key string, route Route) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.route = route
+		entry.expiresAt = expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, route: route, expiresAt: expiresAt})
+	c.bytes += entrySize(key)
+	cacheBytes.Add(float64(entrySize(key)))
+
+	for c.bytes > c.maxBytes && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+		cacheEvictionsTotal.Inc()
+	}
+}
+
+// remove must be called with c.mu held
+func (c *CachingRouteFinder) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entrySize(entry.key)
+	cacheBytes.Sub(float64(entrySize(entry.key)))
+}
+
+// quantize rounds both coordinates of the location to the configured precision,
+// so that nearby coordinates share a cache key
+func (c *CachingRouteFinder) quantize(l Location) string {
+	parts := strings.Split(l.String(), ",")
+	if len(parts) != 2 {
+		return l.String()
+	}
+
+	scale := math.Pow10(c.precision)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return l.String()
+		}
+		parts[i] = strconv.FormatFloat(math.Round(v*scale)/scale, 'f', c.precision, 64)
+	}
+
+	return parts[0] + "," + parts[1]
+}
+
+func entrySize(key string) int64 {
+	return int64(len(key)) + cacheEntryOverhead
+}
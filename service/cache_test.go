@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRouteFinder struct {
+	mu    sync.Mutex
+	calls [][]Location
+	err   error
+}
+
+// FindFastestRoutes answers with a duration of 100 times the call number plus the destination index
+func (f *fakeRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, destinations)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	routes := make([]*Route, len(destinations))
+	for i, d := range destinations {
+		routes[i] = &Route{Destination: d, Duration: float64(len(f.calls)*100 + i), Distance: 10}
+	}
+	return routes, nil
+}
+
+// FindMatrix answers with a duration of 100 times the call number plus the cell index
+func (f *fakeRouteFinder) FindMatrix(ctx context.Context, profile Profile, sources, destinations []Location) (*Matrix, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, destinations)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	matrix := &Matrix{Sources: sources, Destinations: destinations, Cells: make([][]MatrixCell, len(sources))}
+	for i := range sources {
+		matrix.Cells[i] = make([]MatrixCell, len(destinations))
+		for j := range destinations {
+			matrix.Cells[i][j] = MatrixCell{Duration: float64(len(f.calls)*100 + i*len(destinations) + j), Distance: 10}
+		}
+	}
+	return matrix, nil
+}
+
+func (f *fakeRouteFinder) FindRoute(ctx context.Context, profile Profile, source, destination Location, geometry GeometryFormat, steps bool) (*RouteDetails, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, []Location{destination})
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &RouteDetails{Duration: float64(len(f.calls) * 100), Distance: 10}, nil
+}
+
+func (f *fakeRouteFinder) FindTrip(ctx context.Context, profile Profile, source Location, stops []Location, end Location, roundtrip bool) (*Trip, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, stops)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Trip{Order: make([]int, len(stops))}, nil
+}
+
+func TestCachingRouteFinder_OnlyRequestsMissingPairs(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	cache := NewCachingRouteFinder(finder, nil)
+	ctx := context.Background()
+
+	first, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41", "52.52,13.42"})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	second, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.52,13.42", "52.53,13.43", "52.51,13.41"})
+	require.NoError(t, err)
+
+	require.Len(t, finder.calls, 2)
+	assert.Equal(t, []Location{"52.53,13.43"}, finder.calls[1])
+	assert.Equal(t, first[1].Duration, second[0].Duration)
+	assert.Equal(t, float64(200), second[1].Duration)
+	assert.Equal(t, first[0].Duration, second[2].Duration)
+	assert.Equal(t, Location("52.52,13.42"), second[0].Destination)
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, 3, stats.Entries)
+}
+
+func TestCachingRouteFinder_QuantizesCoordinates(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	cache := NewCachingRouteFinder(finder, &CacheConfig{Precision: 3})
+	ctx := context.Background()
+
+	_, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.50001,13.4", []Location{"52.5101,13.4102", "52.51012,13.41018"})
+	require.NoError(t, err)
+	routes, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.40001", []Location{"52.51004,13.41"})
+	require.NoError(t, err)
+
+	require.Len(t, finder.calls, 1)
+	assert.Len(t, finder.calls[0], 1)
+	assert.Equal(t, Location("52.51004,13.41"), routes[0].Destination)
+}
+
+func TestCachingRouteFinder_SeparatesProfiles(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	cache := NewCachingRouteFinder(finder, nil)
+	ctx := context.Background()
+
+	driving, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+	require.NoError(t, err)
+	walking, err := cache.FindFastestRoutes(ctx, ProfileWalking, "52.5,13.4", []Location{"52.51,13.41"})
+	require.NoError(t, err)
+
+	require.Len(t, finder.calls, 2)
+	assert.NotEqual(t, driving[0].Duration, walking[0].Duration)
+}
+
+func TestCachingRouteFinder_ExpiresEntries(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	cache := NewCachingRouteFinder(finder, &CacheConfig{TTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+	require.NoError(t, err)
+
+	assert.Len(t, finder.calls, 2)
+	assert.Equal(t, 1, cache.Stats().Entries)
+}
+
+func TestCachingRouteFinder_EvictsLeastRecentlyUsed(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	key := "driving|52.50000,13.40000|52.51000,13.41000"
+	cache := NewCachingRouteFinder(finder, &CacheConfig{MaxBytes: 2 * entrySize(key)})
+	ctx := context.Background()
+
+	for _, d := range []Location{"52.51,13.41", "52.52,13.42", "52.51,13.41", "52.53,13.43"} {
+		_, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{d})
+		require.NoError(t, err)
+	}
+	_, err := cache.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+	require.NoError(t, err)
+
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Len(t, finder.calls, 3)
+}
+
+func TestCachingRouteFinder_DoesNotCacheErrors(t *testing.T) {
+	finder := &fakeRouteFinder{err: errors.New("osrm down")}
+	cache := NewCachingRouteFinder(finder, nil)
+
+	_, err := cache.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+
+	require.Error(t, err)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestCachingRouteFinder_IncompleteRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []*Route
+	}{
+		{name: "too few routes", routes: []*Route{{Destination: "52.51,13.41"}}},
+		{name: "nil route", routes: []*Route{{Destination: "52.51,13.41"}, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewCachingRouteFinder(&staticRouteFinder{routes: tt.routes}, nil)
+
+			_, err := cache.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", []Location{"52.51,13.41", "52.52,13.42"})
+
+			assert.ErrorIs(t, err, errIncompleteRoutes)
+			assert.Equal(t, 0, cache.Stats().Entries)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CoalescingRouteFinder shares one upstream call between concurrent callers asking for the same
+// profile, source and destination set. The shared call is only cancelled once every caller waiting on it is gone,
+// so one caller cancelling doesn't fail the others.
+type CoalescingRouteFinder struct {
+	routeFinder
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done         chan struct{}
+	cancel       context.CancelFunc
+	waiters      int
+	destinations []Location
+	routes       []*Route
+	err          error
+}
+
+func NewCoalescingRouteFinder(routeFinder routeFinder) *CoalescingRouteFinder {
+	return &CoalescingRouteFinder{
+		routeFinder: routeFinder,
+		flights:     make(map[string]*flight),
+	}
+}
+
+func (c *CoalescingRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	key := flightKey(profile, source, destinations)
+
+	c.mu.Lock()
+	f, ok := c.flights[key]
+	if !ok {
+		// The shared call keeps the values of the first caller's context but not its cancellation
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel, destinations: destinations}
+		c.flights[key] = f
+		go c.fly(flightCtx, key, f, profile, source, destinations)
+	} else {
+		coalescedRequestsTotal.Inc()
+	}
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, f.err
+		}
+		return f.matchRoutes(destinations)
+	case <-ctx.Done():
+		c.leave(key, f)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *CoalescingRouteFinder) fly(ctx context.Context, key string, f *flight, profile Profile, source Location, destinations []Location) {
+	defer f.cancel()
+
+	f.routes, f.err = c.routeFinder.FindFastestRoutes(ctx, profile, source, destinations)
+	if f.err == nil {
+		f.err = checkRoutes(f.routes, len(destinations))
+	}
+
+	c.mu.Lock()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+	c.mu.Unlock()
+
+	close(f.done)
+}
+
+// leave cancels the shared call when the last caller waiting on it is gone
+func (c *CoalescingRouteFinder) leave(key string, f *flight) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+
+	f.cancel()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+}
+
+// flightKey identifies a request by its source and destination set, regardless of destination order
+func flightKey(profile Profile, source Location, destinations []Location) string {
+	sorted := make([]string, len(destinations))
+	for i, d := range destinations {
+		sorted[i] = d.String()
+	}
+	slices.Sort(sorted)
+
+	return string(profile) + "|" + source.String() + "|" + strings.Join(sorted, ";")
+}
+
+// matchRoutes returns a copy of the shared routes in the caller's destination order,
+// so callers can't affect each other by modifying their result
+func (f *flight) matchRoutes(destinations []Location) ([]*Route, error) {
+	byDestination := make(map[Location]*Route, len(f.routes))
+	for i, d := range f.destinations {
+		byDestination[d] = f.routes[i]
+	}
+
+	matched := make([]*Route, len(destinations))
+	for i, d := range destinations {
+		shared, ok := byDestination[d]
+		if !ok {
+			return nil, fmt.Errorf("%w: no route to %s in the shared result", errIncompleteRoutes, d)
+		}
+		route := *shared
+		matched[i] = &route
+	}
+
+	return matched, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingRouteFinder holds every call until release is closed, or until the call's context is done
+type blockingRouteFinder struct {
+	// routeFinder is nil, only FindFastestRoutes is used by these tests
+	routeFinder
+	calls    atomic.Int32
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func newBlockingRouteFinder() *blockingRouteFinder {
+	return &blockingRouteFinder{release: make(chan struct{}), canceled: make(chan struct{}, 1)}
+}
+
+func (f *blockingRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	f.calls.Add(1)
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		f.canceled <- struct{}{}
+		return nil, ctx.Err()
+	}
+
+	routes := make([]*Route, len(destinations))
+	for i, d := range destinations {
+		routes[i] = &Route{Destination: d, Duration: float64(i)}
+	}
+	return routes, nil
+}
+
+func TestCoalescingRouteFinder_SharesConcurrentCalls(t *testing.T) {
+	finder := newBlockingRouteFinder()
+	coalescing := NewCoalescingRouteFinder(finder)
+
+	orders := [][]Location{
+		{"52.51,13.41", "52.52,13.42"},
+		{"52.52,13.42", "52.51,13.41"},
+		{"52.51,13.41", "52.52,13.42"},
+	}
+	results := make([][]*Route, len(orders))
+	var wg sync.WaitGroup
+	for i, destinations := range orders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			routes, err := coalescing.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", destinations)
+			assert.NoError(t, err)
+			results[i] = routes
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		coalescing.mu.Lock()
+		defer coalescing.mu.Unlock()
+		f := coalescing.flights[flightKey(ProfileDriving, "52.5,13.4", orders[0])]
+		return f != nil && f.waiters == len(orders)
+	}, time.Second, time.Millisecond)
+	close(finder.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), finder.calls.Load())
+	for i, routes := range results {
+		require.Len(t, routes, 2)
+		assert.Equal(t, orders[i][0], routes[0].Destination)
+		assert.Equal(t, orders[i][1], routes[1].Destination)
+	}
+	assert.NotSame(t, results[0][0], results[2][0])
+}
+
+func TestCoalescingRouteFinder_CallerCancellationDoesNotAffectOthers(t *testing.T) {
+	finder := newBlockingRouteFinder()
+	coalescing := NewCoalescingRouteFinder(finder)
+	destinations := []Location{"52.51,13.41"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelledErr := make(chan error)
+	go func() {
+		_, err := coalescing.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", destinations)
+		cancelledErr <- err
+	}()
+
+	otherResult := make(chan []*Route)
+	go func() {
+		routes, err := coalescing.FindFastestRoutes(context.Background(), ProfileDriving, "52.5,13.4", destinations)
+		assert.NoError(t, err)
+		otherResult <- routes
+	}()
+
+	require.Eventually(t, func() bool {
+		coalescing.mu.Lock()
+		defer coalescing.mu.Unlock()
+		f := coalescing.flights[flightKey(ProfileDriving, "52.5,13.4", destinations)]
+		return f != nil && f.waiters == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-cancelledErr, context.Canceled)
+
+	close(finder.release)
+	assert.Len(t, <-otherResult, 1)
+	assert.Equal(t, int32(1), finder.calls.Load())
+}
+
+func TestCoalescingRouteFinder_CancelsUpstreamWhenAllCallersLeave(t *testing.T) {
+	finder := newBlockingRouteFinder()
+	coalescing := NewCoalescingRouteFinder(finder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, err := coalescing.FindFastestRoutes(ctx, ProfileDriving, "52.5,13.4", []Location{"52.51,13.41"})
+		errCh <- err
+	}()
+
+	require.Eventually(t, func() bool { return finder.calls.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+	select {
+	case <-finder.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream call was not cancelled")
+	}
+}
+
+func TestCoalescingRouteFinder_IncompleteRoutes(t *testing.T) {
+	coalescing := NewCoalescingRouteFinder(&staticRouteFinder{routes: []*Route{{Destination: "1,1"}, nil}})
+
+	_, err := coalescing.FindFastestRoutes(context.Background(), ProfileDriving, "0,0", []Location{"1,1", "2,2"})
+
+	assert.ErrorIs(t, err, errIncompleteRoutes)
+}
+
+func TestFlight_MatchRoutesMissingDestination(t *testing.T) {
+	f := &flight{destinations: []Location{"1,1"}, routes: []*Route{{Destination: "1,1"}}}
+
+	_, err := f.matchRoutes([]Location{"1,1", "2,2"})
+
+	assert.ErrorIs(t, err, errIncompleteRoutes)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultDetourFactor = 1.3
+
+// defaultSpeeds are the average speeds in meters per second estimates are made with
+var defaultSpeeds = map[Profile]float64{
+	ProfileDriving: 50 / 3.6,
+	ProfileCycling: 15 / 3.6,
+	ProfileWalking: 5 / 3.6,
+}
+
+type FallbackConfig struct {
+	// DetourFactor is the ratio of the road distance to the great-circle distance, defaults to 1.3
+	DetourFactor float64
+	// Speeds are the average speeds in meters per second per profile, missing profiles keep their default
+	Speeds map[Profile]float64
+}
+
+// FallbackRouteFinder estimates the fastest routes from great-circle distances when the routeFinder it wraps
+// fails with ErrUnavailable. Estimated routes have Route.Estimated set. Matrices, routes and trips aren't estimated.
+type FallbackRouteFinder struct {
+	routeFinder
+	detourFactor float64
+	speeds       map[Profile]float64
+}
+
+func NewFallbackRouteFinder(routeFinder routeFinder, cfg *FallbackConfig) *FallbackRouteFinder {
+	if cfg == nil {
+		cfg = &FallbackConfig{}
+	}
+
+	detourFactor := defaultDetourFactor
+	if cfg.DetourFactor >= 1 {
+		detourFactor = cfg.DetourFactor
+	}
+
+	speeds := make(map[Profile]float64, len(defaultSpeeds))
+	for p, speed := range defaultSpeeds {
+		speeds[p] = speed
+	}
+	for p, speed := range cfg.Speeds {
+		if speed > 0 {
+			speeds[p] = speed
+		}
+	}
+
+	return &FallbackRouteFinder{
+		routeFinder:  routeFinder,
+		detourFactor: detourFactor,
+		speeds:       speeds,
+	}
+}
+
+func (f *FallbackRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	routes, err := f.routeFinder.FindFastestRoutes(ctx, profile, source, destinations)
+	if err == nil || !errors.Is(err, ErrUnavailable) || ctx.Err() != nil {
+		return routes, err
+	}
+
+	speed, ok := f.speeds[profile]
+	if !ok {
+		return nil, err
+	}
+	from, parseErr := source.Coordinate()
+	if parseErr != nil {
+		return nil, err
+	}
+
+	fallbackEstimatesTotal.WithLabelValues(string(profile)).Inc()
+	trace.SpanFromContext(ctx).AddEvent("route.fallback", trace.WithAttributes(
+		attribute.String("route.fallback.cause", err.Error()),
+	))
+
+	return f.estimate(from, destinations, speed), nil
+}
+
+func (f *FallbackRouteFinder) estimate(source Coordinate, destinations []Location, speed float64) []*Route {
+	routes := make([]*Route, len(destinations))
+	for i, d := range destinations {
+		routes[i] = &Route{Destination: d, Estimated: true}
+		to, err := d.Coordinate()
+		if err != nil {
+			routes[i].Unreachable = true
+			continue
+		}
+		routes[i].Distance = GreatCircleDistance(source, to) * f.detourFactor
+		routes[i].Duration = routes[i].Distance / speed
+	}
+	return routes
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackRouteFinder_FindFastestRoutes(t *testing.T) {
+	unavailable := fmt.Errorf("%w: connection refused", ErrUnavailable)
+	destinations := []Location{"0,0.02", "0,0.01"}
+
+	tests := []struct {
+		name          string
+		err           error
+		profile       Profile
+		wantErr       error
+		wantEstimated bool
+		// wantSpeed is the speed in meters per second the estimates are expected to use
+		wantSpeed float64
+	}{
+		{name: "available", err: nil},
+		{name: "other errors are returned", err: ErrNoRoute, wantErr: ErrNoRoute},
+		{name: "unavailable driving", err: unavailable, profile: ProfileDriving, wantEstimated: true, wantSpeed: 20},
+		{name: "unavailable walking keeps default speed", err: unavailable, profile: ProfileWalking, wantEstimated: true, wantSpeed: 5 / 3.6},
+		{name: "unknown profile", err: unavailable, profile: "boat", wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := NewFallbackRouteFinder(&fakeRouteFinder{err: tt.err}, &FallbackConfig{
+				DetourFactor: 1.5,
+				Speeds:       map[Profile]float64{ProfileDriving: 20},
+			})
+
+			routes, err := finder.FindFastestRoutes(context.Background(), tt.profile, "0,0", destinations)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, routes, len(destinations))
+			for i, r := range routes {
+				assert.Equal(t, destinations[i], r.Destination)
+				assert.Equal(t, tt.wantEstimated, r.Estimated)
+				if !tt.wantEstimated {
+					continue
+				}
+				to, err := destinations[i].Coordinate()
+				require.NoError(t, err)
+				wantDistance := GreatCircleDistance(Coordinate{}, to) * 1.5
+				assert.InDelta(t, wantDistance, r.Distance, 1e-6)
+				assert.InDelta(t, wantDistance/tt.wantSpeed, r.Duration, 1e-6)
+			}
+		})
+	}
+}
+
+func TestFallbackRouteFinder_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	finder := NewFallbackRouteFinder(&fakeRouteFinder{err: fmt.Errorf("%w: %w", ErrUnavailable, context.Canceled)}, nil)
+
+	_, err := finder.FindFastestRoutes(ctx, ProfileDriving, "0,0", []Location{"1,1"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetFastestRoutes_Estimated(t *testing.T) {
+	finder := NewFallbackRouteFinder(&fakeRouteFinder{err: ErrUnavailable}, nil)
+
+	result, err := NewRouteService(finder).GetFastestRoutes(context.Background(), "0,0", destinationsAt("0,0.02", "0,0.01"), nil)
+
+	require.NoError(t, err)
+	assert.True(t, result.Estimated)
+	require.Len(t, result.Routes, 2)
+	assert.Equal(t, Location("0,0.01"), result.Routes[0].Destination)
+	assert.Equal(t, Location("0,0.02"), result.Routes[1].Destination)
+	assert.Equal(t, result.Routes[0].Duration, result.Routes[0].Score)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGreatCircleDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Coordinate
+		want float64
+	}{
+		{name: "same point", a: Coordinate{Lat: 52.517037, Lon: 13.38886}, b: Coordinate{Lat: 52.517037, Lon: 13.38886}, want: 0},
+		{name: "Berlin to Paris", a: Coordinate{Lat: 52.520008, Lon: 13.404954}, b: Coordinate{Lat: 48.856613, Lon: 2.352222}, want: 877_500},
+		{name: "across the antimeridian", a: Coordinate{Lat: 0, Lon: 179.5}, b: Coordinate{Lat: 0, Lon: -179.5}, want: 111_195},
+		{name: "antipodes", a: Coordinate{Lat: 0, Lon: 0}, b: Coordinate{Lat: 0, Lon: 180}, want: 20_015_115},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, GreatCircleDistance(tt.a, tt.b), tt.want*0.001+1)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	destinationsPerRequest = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "route_destinations_per_request",
+		Help:    "Number of destinations asked for in one route request.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+	})
+
+	duplicateDestinationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "route_duplicate_destinations_total",
+		Help: "Number of destinations left out of upstream calls because the same normalized location was asked for in the same request.",
+	})
+
+	cacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "route_cache_lookups_total",
+		Help: "Number of route cache lookups per source-destination pair by result (hit, miss).",
+	}, []string{"result"})
+
+	cacheEvictionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "route_cache_evictions_total",
+		Help: "Number of route cache entries evicted to stay within the memory bound.",
+	})
+
+	cacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "route_cache_bytes",
+		Help: "Approximate memory used by the route cache.",
+	})
+
+	coalescedRequestsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "route_coalesced_requests_total",
+		Help: "Number of route requests that joined an identical in-flight upstream call.",
+	})
+
+	batchDestinations = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "route_batch_destinations",
+		Help:    "Number of distinct destinations merged into one batched upstream call.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+	})
+
+	batchCallers = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "route_batch_callers",
+		Help:    "Number of route requests merged into one batched upstream call.",
+		Buckets: []float64{1, 2, 3, 5, 10, 20, 50},
+	})
+
+	fallbackEstimatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "route_fallback_estimates_total",
+		Help: "Number of route requests answered with great-circle estimates while the routing engine was unavailable, by profile.",
+	}, []string{"profile"})
+)
@@ -1,17 +1,256 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
+// Destination is a destination of RouteService.GetFastestRoutes
+type Destination struct {
+	Location Location
+	// ID is chosen by the caller and copied to Route.ID, it needn't be unique
+	ID string
+}
+
 type Route struct {
 	Destination Location
-	Distance    float64
-	Duration    float64
+	// ID is the Destination.ID the route was asked for with
+	ID string
+	// Index is the position of the destination in the destinations the route was asked for with
+	Index    int
+	Distance float64
+	Duration float64
+	// SnappedLocation is the point on the road network the destination was snapped to, empty if unknown
+	SnappedLocation Location
+	// SnapDistance is the distance in meters between the destination and SnappedLocation
+	SnapDistance float64
+	// SnapTooFar is set when SnapDistance exceeds RouteOptions.MaxSnapDistance
+	SnapTooFar bool
+	// Unreachable is set when no route exists to the destination, Distance and Duration are then meaningless
+	Unreachable bool
+	// Score is the value routes are ranked by, lowest first, see RankingStrategy. It is 0 for unreachable routes.
+	Score float64
+	// Estimated is set when Distance and Duration were estimated from the great-circle distance,
+	// see FallbackRouteFinder
+	Estimated bool
+}
+
+// MatrixCell is the route from one source to one destination of a Matrix
+type MatrixCell struct {
+	Distance float64
+	Duration float64
+	// Unreachable is set when no route exists between the source and the destination
+	Unreachable bool
+}
+
+// Matrix holds the routes from every source to every destination
+type Matrix struct {
+	Sources      []Location
+	Destinations []Location
+	// Cells[i][j] is the route from Sources[i] to Destinations[j]
+	Cells [][]MatrixCell
+}
+
+// GeometryFormat is the encoding of a route geometry
+type GeometryFormat string
+
+const (
+	// GeometryPolyline is an encoded polyline with a precision of 5 decimal places
+	GeometryPolyline GeometryFormat = "polyline"
+	// GeometryGeoJSON is a GeoJSON LineString
+	GeometryGeoJSON GeometryFormat = "geojson"
+)
+
+func (f GeometryFormat) Validate() error {
+	switch f {
+	case GeometryPolyline, GeometryGeoJSON:
+		return nil
+	default:
+		return fmt.Errorf("geometry must be one of %s, %s", GeometryPolyline, GeometryGeoJSON)
+	}
+}
+
+// ErrNoRoute is returned when no route exists between the source and the destination
+var ErrNoRoute = errors.New("no route found")
+
+// RouteDetails is the full route from a source to a destination, as needed to draw it
+type RouteDetails struct {
+	Distance float64
+	Duration float64
+	Geometry Geometry
+	// Steps is only set when requested with RouteOptions.Steps
+	Steps []RouteStep
+}
+
+// Geometry is the path of a route, only the field of the requested GeometryFormat is set
+type Geometry struct {
+	Polyline    string
+	Coordinates []Coordinate
+}
+
+// RouteStep is one maneuver of a route and the way traveled until the next one
+type RouteStep struct {
+	Instruction string
+	// Name is the name of the way traveled along
+	Name     string
+	Distance float64
+	Duration float64
+	// Maneuver is the kind of maneuver, e.g. depart, turn, roundabout, arrive
+	Maneuver string
+	// Modifier is the direction of the maneuver, e.g. left, slight right, straight
+	Modifier string
+	Location Coordinate
+}
+
+// ErrNoTrip is returned when the stops can't all be visited in one trip, e.g. when some aren't connected by road
+var ErrNoTrip = errors.New("no trip found")
+
+// Trip is the order stops are visited in, starting from a source
+type Trip struct {
+	// Order lists the indices of the stops in visiting order
+	Order []int
+	// Legs go from the source through the stops in Order. Round trips end with a leg back to the source,
+	// trips with a fixed end with a leg to it.
+	Legs     []TripLeg
+	Distance float64
+	Duration float64
+}
+
+type TripLeg struct {
+	From     Location
+	To       Location
+	Distance float64
+	Duration float64
+}
+
+type TripOptions struct {
+	// Profile is the means of transport, defaults to ProfileDriving
+	Profile Profile
+	// OneWay ends the trip at the last stop instead of returning to the source
+	OneWay bool
+	// End is a fixed location the trip ends at after visiting all stops, it implies OneWay
+	End Location
+}
+
+func (o *TripOptions) profile() Profile {
+	if o.Profile == "" {
+		return ProfileDriving
+	}
+	return o.Profile
+}
+
+// SnapPolicy decides what happens to destinations snapping further than RouteOptions.MaxSnapDistance
+type SnapPolicy string
+
+const (
+	// SnapPolicyFlag keeps the destination and sets Route.SnapTooFar
+	SnapPolicyFlag SnapPolicy = "flag"
+	// SnapPolicyExclude drops the destination from the result
+	SnapPolicyExclude SnapPolicy = "exclude"
+)
+
+func (p SnapPolicy) Validate() error {
+	switch p {
+	case SnapPolicyFlag, SnapPolicyExclude:
+		return nil
+	default:
+		return fmt.Errorf("snap policy must be one of %s, %s", SnapPolicyFlag, SnapPolicyExclude)
+	}
+}
+
+// Profile is the means of transport routes are computed for
+type Profile string
+
+const (
+	ProfileDriving Profile = "driving"
+	ProfileCycling Profile = "cycling"
+	ProfileWalking Profile = "walking"
+)
+
+// ErrUnsupportedProfile is returned by route finders that have no routing data for the requested profile
+var ErrUnsupportedProfile = errors.New("travel profile is not supported")
+
+func (p Profile) Validate() error {
+	switch p {
+	case ProfileDriving, ProfileCycling, ProfileWalking:
+		return nil
+	default:
+		return fmt.Errorf("profile must be one of %s, %s, %s", ProfileDriving, ProfileCycling, ProfileWalking)
+	}
+}
+
+type RouteOptions struct {
+	// Profile is the means of transport, defaults to ProfileDriving
+	Profile Profile
+	// MaxSnapDistance is the max distance in meters between a destination and the road it snaps to, 0 disables the check
+	MaxSnapDistance float64
+	// SnapPolicy applies to destinations snapping further than MaxSnapDistance, defaults to SnapPolicyFlag
+	SnapPolicy SnapPolicy
+	// Geometry is the encoding of the geometry returned by GetRoute, defaults to GeometryPolyline
+	Geometry GeometryFormat
+	// Steps asks GetRoute for turn-by-turn steps
+	Steps bool
+	// Limit is the max number of routes GetFastestRoutes returns, 0 means no limit
+	Limit int
+	// MaxDuration and MaxDistance leave out the routes taking longer in seconds or going further in meters,
+	// and the unreachable destinations. 0 means no limit.
+	MaxDuration float64
+	MaxDistance float64
+	// Ranking orders the routes of GetFastestRoutes, defaults to RankingDuration
+	Ranking RankingStrategy
+	// DurationWeight and DistanceWeight weigh seconds and meters for RankingWeighted
+	DurationWeight float64
+	DistanceWeight float64
+	// Tolerance is the difference in seconds under which RankingTolerance considers durations equal
+	Tolerance float64
+}
+
+func (o *RouteOptions) ranking() RankingStrategy {
+	if o.Ranking == "" {
+		return RankingDuration
+	}
+	return o.Ranking
+}
+
+// FastestRoutes is the result of GetFastestRoutes
+type FastestRoutes struct {
+	// Ranking is the strategy the routes were ranked with
+	Ranking RankingStrategy
+	Routes  []*Route
+	// Filtered counts the destinations left out of Routes, by reason
+	Filtered FilterCounts
+	// Estimated is set when the routes were estimated rather than computed by the routing engine
+	Estimated bool
+}
+
+// FilterCounts counts the destinations left out of FastestRoutes. Every destination is counted once,
+// for the first of these reasons that applies.
+type FilterCounts struct {
+	// SnapTooFar counts the destinations excluded by SnapPolicyExclude
+	SnapTooFar int
+	// Unreachable counts the unreachable destinations left out because of MaxDuration or MaxDistance
+	Unreachable int
+	MaxDuration int
+	MaxDistance int
+	// Limit counts the routes beyond RouteOptions.Limit
+	Limit int
+}
+
+func (c FilterCounts) Total() int {
+	return c.SnapTooFar + c.Unreachable + c.MaxDuration + c.MaxDistance + c.Limit
+}
+
+func (o *RouteOptions) profile() Profile {
+	if o.Profile == "" {
+		return ProfileDriving
+	}
+	return o.Profile
 }
 
+// Location is a "latitude,longitude" pair, the canonical order used inside the service
 type Location string
 
 func (l Location) String() string {
@@ -20,28 +259,110 @@ func (l Location) String() string {
 
 // Validate performs domain validation on the location (lat/lng format and ranges)
 func (l Location) Validate() error {
-	if l == "" {
-		return fmt.Errorf("location is required")
+	_, err := l.Coordinate()
+	return err
+}
+
+// Coordinate parses the location
+func (l Location) Coordinate() (Coordinate, error) {
+	return ParseLocation(l.String(), CoordOrderLatLon)
+}
+
+// LocationPrecision is the number of decimal places locations are normalized to, ~0.11m at the equator
+const LocationPrecision = 6
+
+// Normalize returns the location without whitespace, rounded to LocationPrecision decimal places,
+// so that differently written locations of the same point are equal
+func (l Location) Normalize() (Location, error) {
+	c, err := l.Coordinate()
+	if err != nil {
+		return "", err
 	}
+	return c.Round(LocationPrecision).Location(), nil
+}
 
-	parts := strings.Split(l.String(), ",")
+// CoordOrder is the order of the two numbers of a coordinate pair
+type CoordOrder string
+
+const (
+	CoordOrderLatLon CoordOrder = "latlon"
+	// CoordOrderLonLat is the order used by OSRM and GeoJSON
+	CoordOrderLonLat CoordOrder = "lonlat"
+)
+
+func (o CoordOrder) Validate() error {
+	switch o {
+	case CoordOrderLatLon, CoordOrderLonLat:
+		return nil
+	default:
+		return fmt.Errorf("coordinate order must be one of %s, %s", CoordOrderLatLon, CoordOrderLonLat)
+	}
+}
+
+type Coordinate struct {
+	Lat float64
+	Lon float64
+}
+
+// ParseLocation parses a coordinate pair given in the given order and checks its ranges
+func ParseLocation(s string, order CoordOrder) (Coordinate, error) {
+	if s == "" {
+		return Coordinate{}, fmt.Errorf("location is required")
+	}
+
+	format := "latitude,longitude"
+	if order == CoordOrderLonLat {
+		format = "longitude,latitude"
+	}
+
+	parts := strings.Split(s, ",")
 	if len(parts) != 2 {
-		return fmt.Errorf("location must be in the format of latitude,longitude")
+		return Coordinate{}, fmt.Errorf("location must be in the format of %s", format)
 	}
-	latitude, err := strconv.ParseFloat(parts[0], 64)
+
+	latPart, lonPart := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if order == CoordOrderLonLat {
+		latPart, lonPart = lonPart, latPart
+	}
+
+	latitude, err := strconv.ParseFloat(latPart, 64)
 	if err != nil {
-		return fmt.Errorf("invalid latitude: %w", err)
+		return Coordinate{}, fmt.Errorf("invalid latitude: %w", err)
 	}
-	longitude, err := strconv.ParseFloat(parts[1], 64)
+	longitude, err := strconv.ParseFloat(lonPart, 64)
 	if err != nil {
-		return fmt.Errorf("invalid longitude: %w", err)
+		return Coordinate{}, fmt.Errorf("invalid longitude: %w", err)
 	}
 	if latitude <= -90 || latitude >= 90 {
-		return fmt.Errorf("invalid latitude: %f", latitude)
+		return Coordinate{}, fmt.Errorf("invalid latitude: %f", latitude)
 	}
 	if longitude <= -180 || longitude >= 180 {
-		return fmt.Errorf("invalid longitude: %f", longitude)
+		return Coordinate{}, fmt.Errorf("invalid longitude: %f", longitude)
 	}
 
-	return nil
+	return Coordinate{Lat: latitude, Lon: longitude}, nil
+}
+
+// Location formats the coordinate in the canonical latitude,longitude order
+func (c Coordinate) Location() Location {
+	return Location(c.Format(CoordOrderLatLon))
+}
+
+// Round rounds both numbers of the coordinate to the given number of decimal places
+func (c Coordinate) Round(precision int) Coordinate {
+	scale := math.Pow10(precision)
+	return Coordinate{Lat: math.Round(c.Lat*scale) / scale, Lon: math.Round(c.Lon*scale) / scale}
 }
+
+// Format formats the coordinate in the given order
+func (c Coordinate) Format(order CoordOrder) string {
+	lat := strconv.FormatFloat(c.Lat, 'f', -1, 64)
+	lon := strconv.FormatFloat(c.Lon, 'f', -1, 64)
+	if order == CoordOrderLonLat {
+		return lon + "," + lat
+	}
+	return lat + "," + lon
+}
+
+// ErrUnavailable is returned when the routing engine can't be reached, e.g. on network errors or an open circuit
+var ErrUnavailable = errors.New("routing engine unavailable")
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/planner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// PlanVehicle is a vehicle available to GetPlan, times are in seconds from an epoch shared by the whole plan
+type PlanVehicle struct {
+	Start Location
+	End   Location
+	// Capacity is the max total demand of the stops on the route, 0 means unlimited
+	Capacity float64
+	Shift    TimeWindow
+}
+
+// PlanStop is a stop to visit in GetPlan, times are in seconds from an epoch shared by the whole plan
+type PlanStop struct {
+	Location    Location
+	Demand      float64
+	Window      TimeWindow
+	ServiceTime float64
+}
+
+// TimeWindow is a time interval in seconds, an End of 0 means no upper bound
+type TimeWindow struct {
+	Start float64
+	End   float64
+}
+
+// Plan is the result of GetPlan, vehicles and stops are referred to by their index in the request
+type Plan struct {
+	// Routes holds the routes of the vehicles that visit at least one stop
+	Routes []PlanRoute
+	// Unassigned lists the stops no vehicle can visit within the constraints
+	Unassigned []int
+	Distance   float64
+	Duration   float64
+}
+
+type PlanRoute struct {
+	Vehicle int
+	Visits  []PlanVisit
+	// Departure is when the vehicle leaves its start depot, as late as possible without waiting at the first stop
+	Departure float64
+	// Return is when the vehicle reaches its end depot
+	Return   float64
+	Distance float64
+	// Duration is the time from Departure to Return, including service and waiting times
+	Duration float64
+	Load     float64
+}
+
+type PlanVisit struct {
+	Stop    int
+	Arrival float64
+	// Start is when the service begins, after waiting for the time window to open
+	Start     float64
+	Departure float64
+}
+
+type PlanOptions struct {
+	// Profile is the means of transport, defaults to ProfileDriving
+	Profile Profile
+	// Seed drives the planner's local search, the same stops, vehicles and seed always produce the same plan
+	Seed uint64
+}
+
+func (o *PlanOptions) profile() Profile {
+	if o.Profile == "" {
+		return ProfileDriving
+	}
+	return o.Profile
+}
+
+func (s *routeServiceImpl) GetPlan(ctx context.Context, vehicles []PlanVehicle, stops []PlanStop, opts *PlanOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &PlanOptions{}
+	}
+	profile := opts.profile()
+
+	ctx, span := tracer.Start(ctx, "RouteService.GetPlan")
+	defer span.End()
+
+	// Depots are usually shared by vehicles, and stops may share an address, so each location is routed once
+	locations := make([]Location, 0, 2*len(vehicles)+len(stops))
+	indices := make(map[Location]int, cap(locations))
+	index := func(l Location) int {
+		i, ok := indices[l]
+		if !ok {
+			i = len(locations)
+			indices[l] = i
+			locations = append(locations, l)
+		}
+		return i
+	}
+
+	problem := &planner.Problem{
+		Vehicles: make([]planner.Vehicle, len(vehicles)),
+		Stops:    make([]planner.Stop, len(stops)),
+	}
+	for i, v := range vehicles {
+		problem.Vehicles[i] = planner.Vehicle{Start: index(v.Start), End: index(v.End), Capacity: v.Capacity, Shift: planner.Window(v.Shift)}
+	}
+	for i, stop := range stops {
+		problem.Stops[i] = planner.Stop{Location: index(stop.Location), Demand: stop.Demand, Window: planner.Window(stop.Window), ServiceTime: stop.ServiceTime}
+	}
+
+	span.SetAttributes(
+		attribute.Int("route.vehicles", len(vehicles)),
+		attribute.Int("route.stops", len(stops)),
+		attribute.Int("route.locations", len(locations)),
+		attribute.String("route.profile", string(profile)),
+	)
+
+	matrix, err := s.routeFinder.FindMatrix(ctx, profile, locations, locations)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	problem.Durations = make([][]float64, len(locations))
+	problem.Distances = make([][]float64, len(locations))
+	for i, row := range matrix.Cells {
+		problem.Durations[i] = make([]float64, len(row))
+		problem.Distances[i] = make([]float64, len(row))
+		for j, cell := range row {
+			if cell.Unreachable {
+				problem.Durations[i][j], problem.Distances[i][j] = math.Inf(1), math.Inf(1)
+				continue
+			}
+			problem.Durations[i][j], problem.Distances[i][j] = cell.Duration, cell.Distance
+		}
+	}
+
+	plan, err := planner.Solve(problem, &planner.Config{Seed: opts.Seed})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("route.unassigned", len(plan.Unassigned)))
+	return toPlan(plan), nil
+}
+
+func toPlan(p *planner.Plan) *Plan {
+	plan := &Plan{
+		Routes:     make([]PlanRoute, len(p.Routes)),
+		Unassigned: p.Unassigned,
+		Distance:   p.Distance,
+		Duration:   p.Duration,
+	}
+	for i, r := range p.Routes {
+		visits := make([]PlanVisit, len(r.Visits))
+		for j, v := range r.Visits {
+			visits[j] = PlanVisit(v)
+		}
+		plan.Routes[i] = PlanRoute{
+			Vehicle:   r.Vehicle,
+			Visits:    visits,
+			Departure: r.Departure,
+			Return:    r.Return,
+			Distance:  r.Distance,
+			Duration:  r.Duration,
+			Load:      r.Load,
+		}
+	}
+	return plan
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// RankingStrategy decides the order of the routes returned by GetFastestRoutes
+type RankingStrategy string
+
+const (
+	// RankingDuration ranks by duration, then distance. The score is the duration.
+	RankingDuration RankingStrategy = "duration"
+	// RankingDistance ranks by distance, then duration. The score is the distance.
+	RankingDistance RankingStrategy = "distance"
+	// RankingWeighted ranks by RouteOptions.DurationWeight*duration + RouteOptions.DistanceWeight*distance,
+	// which is the score
+	RankingWeighted RankingStrategy = "weighted"
+	// RankingTolerance ranks by duration, considering durations within RouteOptions.Tolerance seconds of the
+	// fastest route of a group equal, and by distance within a group. The score is the duration of the fastest route of the group.
+	RankingTolerance RankingStrategy = "tolerance"
+)
+
+func (s RankingStrategy) Validate() error {
+	if _, ok := rankers[s]; !ok {
+		return fmt.Errorf("ranking must be one of %s, %s, %s, %s", RankingDuration, RankingDistance, RankingWeighted, RankingTolerance)
+	}
+	return nil
+}
+
+// ranker sets the score of the reachable routes and sorts them, lowest score first
+type ranker func(routes []*Route, opts *RouteOptions)
+
+var rankers = map[RankingStrategy]ranker{
+	RankingDuration:  rankByDuration,
+	RankingDistance:  rankByDistance,
+	RankingWeighted:  rankByWeight,
+	RankingTolerance: rankWithTolerance,
+}
+
+// rank sorts the routes with the strategy of opts, unreachable routes last in their original order
+func rank(routes []*Route, opts *RouteOptions) {
+	slices.SortStableFunc(routes, func(a, b *Route) int {
+		switch {
+		case a.Unreachable == b.Unreachable:
+			return 0
+		case b.Unreachable:
+			return -1
+		default:
+			return 1
+		}
+	})
+	reachable := slices.IndexFunc(routes, func(r *Route) bool { return r.Unreachable })
+	if reachable < 0 {
+		reachable = len(routes)
+	}
+
+	ranker, ok := rankers[opts.ranking()]
+	if !ok {
+		ranker = rankByDuration
+	}
+	ranker(routes[:reachable], opts)
+}
+
+func byDurationThenDistance(a, b *Route) int {
+	return cmp.Or(cmp.Compare(a.Duration, b.Duration), cmp.Compare(a.Distance, b.Distance))
+}
+
+func rankByDuration(routes []*Route, opts *RouteOptions) {
+	for _, r := range routes {
+		r.Score = r.Duration
+	}
+	slices.SortStableFunc(routes, byDurationThenDistance)
+}
+
+func rankByDistance(routes []*Route, opts *RouteOptions) {
+	for _, r := range routes {
+		r.Score = r.Distance
+	}
+	slices.SortStableFunc(routes, func(a, b *Route) int {
+		return cmp.Or(cmp.Compare(a.Distance, b.Distance), cmp.Compare(a.Duration, b.Duration))
+	})
+}
+
+func rankByWeight(routes []*Route, opts *RouteOptions) {
+	for _, r := range routes {
+		r.Score = opts.DurationWeight*r.Duration + opts.DistanceWeight*r.Distance
+	}
+	slices.SortStableFunc(routes, func(a, b *Route) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), byDurationThenDistance(a, b))
+	})
+}
+
+func rankWithTolerance(routes []*Route, opts *RouteOptions) {
+	slices.SortStableFunc(routes, byDurationThenDistance)
+
+	// Groups start at the fastest route not in a group yet, so a chain of close durations can't grow a group without bound
+	for start := 0; start < len(routes); {
+		end := start + 1
+		for end < len(routes) && routes[end].Duration-routes[start].Duration <= opts.Tolerance {
+			end++
+		}
+		for _, r := range routes[start:end] {
+			r.Score = routes[start].Duration
+		}
+		slices.SortStableFunc(routes[start:end], func(a, b *Route) int {
+			return cmp.Or(cmp.Compare(a.Distance, b.Distance), cmp.Compare(a.Duration, b.Duration))
+		})
+		start = end
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFastestRoutes_Ranking(t *testing.T) {
+	newFinder := func() *staticRouteFinder {
+		return &staticRouteFinder{routes: []*Route{
+			{Destination: "1,1", Duration: 100, Distance: 3000},
+			{Destination: "2,2", Unreachable: true},
+			{Destination: "3,3", Duration: 130, Distance: 1000},
+			{Destination: "4,4", Duration: 108, Distance: 2000},
+			{Destination: "5,5", Duration: 400, Distance: 900},
+			{Destination: "6,6", Duration: 120, Distance: 1500},
+		}}
+	}
+	destinations := []Location{"1,1", "2,2", "3,3", "4,4", "5,5", "6,6"}
+
+	tests := []struct {
+		name     string
+		opts     *RouteOptions
+		expected []Location
+		scores   []float64
+	}{
+		{
+			name:     "duration by default",
+			opts:     nil,
+			expected: []Location{"1,1", "4,4", "6,6", "3,3", "5,5", "2,2"},
+			scores:   []float64{100, 108, 120, 130, 400, 0},
+		},
+		{
+			name:     "distance",
+			opts:     &RouteOptions{Ranking: RankingDistance},
+			expected: []Location{"5,5", "3,3", "6,6", "4,4", "1,1", "2,2"},
+			scores:   []float64{900, 1000, 1500, 2000, 3000, 0},
+		},
+		{
+			name:     "weighted",
+			opts:     &RouteOptions{Ranking: RankingWeighted, DurationWeight: 1, DistanceWeight: 0.1},
+			expected: []Location{"3,3", "6,6", "4,4", "1,1", "5,5", "2,2"},
+			scores:   []float64{230, 270, 308, 400, 490, 0},
+		},
+		{
+			name: "tolerance",
+			opts: &RouteOptions{Ranking: RankingTolerance, Tolerance: 10},
+			// 100 and 108 are in the group of 100, 120 and 130 in the group of 120
+			expected: []Location{"4,4", "1,1", "3,3", "6,6", "5,5", "2,2"},
+			scores:   []float64{100, 100, 120, 120, 400, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewRouteService(newFinder()).GetFastestRoutes(context.Background(), "0,0", destinationsAt(destinations...), tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, routeDestinations(result.Routes))
+			scores := make([]float64, len(result.Routes))
+			for i, r := range result.Routes {
+				scores[i] = r.Score
+			}
+			assert.InDeltaSlice(t, tt.scores, scores, 1e-9)
+		})
+	}
+}
@@ -2,11 +2,33 @@ package service
 
 import (
 	"context"
-	"sort"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+var tracer = otel.Tracer("github.com/mrasoolmirzaei/delivery-route-system/service")
+
 type RouteService interface {
-	GetFastestRoutes(ctx context.Context, source Location, destinations []Location) ([]*Route, error)
+	// GetFastestRoutes returns the routes to the destinations ranked with the strategy of opts, by default by duration
+	// then distance, with unreachable destinations last. The filters and limit of opts apply after ranking. opts may be nil.
+	// Destinations are normalized and duplicates are routed once. Every route keeps the location, ID and index of its destination.
+	GetFastestRoutes(ctx context.Context, source Location, destinations []Destination, opts *RouteOptions) (*FastestRoutes, error)
+	// GetMatrix returns the routes from every source to every destination, in the given order.
+	// Only the profile of opts is used, opts may be nil.
+	GetMatrix(ctx context.Context, sources, destinations []Location, opts *RouteOptions) (*Matrix, error)
+	// GetRoute returns the geometry, duration and distance of the route from source to destination,
+	// and its steps if requested. Only the profile, geometry and steps of opts are used, opts may be nil.
+	GetRoute(ctx context.Context, source, destination Location, opts *RouteOptions) (*RouteDetails, error)
+	// GetTrip returns the fastest order to visit the stops in, starting from source. opts may be nil,
+	// the trip then returns to source.
+	GetTrip(ctx context.Context, source Location, stops []Location, opts *TripOptions) (*Trip, error)
+	// GetPlan assigns the stops to the vehicles and orders them, using the durations between all their locations.
+	// The routes and unassigned stops refer to vehicles and stops by index. opts may be nil.
+	GetPlan(ctx context.Context, vehicles []PlanVehicle, stops []PlanStop, opts *PlanOptions) (*Plan, error)
 }
 
 type routeServiceImpl struct {
@@ -14,25 +36,234 @@ type routeServiceImpl struct {
 }
 
 type routeFinder interface {
-	FindFastestRoutes(ctx context.Context, source Location, destinations []Location) ([]*Route, error)
+	// FindFastestRoutes returns one route per destination, in the order of destinations
+	FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error)
+	FindMatrix(ctx context.Context, profile Profile, sources, destinations []Location) (*Matrix, error)
+	FindRoute(ctx context.Context, profile Profile, source, destination Location, geometry GeometryFormat, steps bool) (*RouteDetails, error)
+	// FindTrip visits the stops starting from source, ending at end if not empty, or back at source if roundtrip
+	FindTrip(ctx context.Context, profile Profile, source Location, stops []Location, end Location, roundtrip bool) (*Trip, error)
 }
 
 func NewRouteService(routeFinder routeFinder) RouteService {
 	return &routeServiceImpl{routeFinder: routeFinder}
 }
 
-func (s *routeServiceImpl) GetFastestRoutes(ctx context.Context, source Location, destinations []Location) ([]*Route, error) {
-	routes, err := s.routeFinder.FindFastestRoutes(ctx, source, destinations)
+func (s *routeServiceImpl) GetFastestRoutes(ctx context.Context, source Location, destinations []Destination, opts *RouteOptions) (*FastestRoutes, error) {
+	if opts == nil {
+		opts = &RouteOptions{}
+	}
+	profile := opts.profile()
+
+	ctx, span := tracer.Start(ctx, "RouteService.GetFastestRoutes")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("route.destinations", len(destinations)),
+		attribute.String("route.profile", string(profile)),
+		attribute.String("route.ranking", string(opts.ranking())),
+	)
+
+	destinationsPerRequest.Observe(float64(len(destinations)))
+
+	if normalized, err := source.Normalize(); err == nil {
+		source = normalized
+	}
+	unique, positions := dedupe(destinations)
+	span.SetAttributes(attribute.Int("route.unique_destinations", len(unique)))
+	duplicateDestinationsTotal.Add(float64(len(destinations) - len(unique)))
+
+	found, err := s.routeFinder.FindFastestRoutes(ctx, profile, source, unique)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
+	if err := checkRoutes(found, len(unique)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	// Every destination gets its own copy of the route, duplicates included
+	routes := make([]*Route, len(destinations))
+	for i, d := range destinations {
+		route := *found[positions[i]]
+		route.Destination, route.ID, route.Index = d.Location, d.ID, i
+		routes[i] = &route
+	}
 
-	sort.Slice(routes, func(i, j int) bool {
-		if routes[i].Duration == routes[j].Duration {
-			return routes[i].Distance < routes[j].Distance
+	result := &FastestRoutes{Ranking: opts.ranking()}
+	for _, r := range routes {
+		if r.Estimated {
+			result.Estimated = true
+			break
 		}
-		return routes[i].Duration < routes[j].Duration
-	})
+	}
+	span.SetAttributes(attribute.Bool("route.estimated", result.Estimated))
+	routes = applySnapPolicy(routes, opts, &result.Filtered)
+	rank(routes, opts)
+
+	result.Routes = applyFilters(routes, opts, &result.Filtered)
+	span.SetAttributes(attribute.Int("route.filtered", result.Filtered.Total()))
+
+	return result, nil
+}
+
+func (s *routeServiceImpl) GetMatrix(ctx context.Context, sources, destinations []Location, opts *RouteOptions) (*Matrix, error) {
+	if opts == nil {
+		opts = &RouteOptions{}
+	}
+	profile := opts.profile()
+
+	ctx, span := tracer.Start(ctx, "RouteService.GetMatrix")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("route.sources", len(sources)),
+		attribute.Int("route.destinations", len(destinations)),
+		attribute.String("route.profile", string(profile)),
+	)
 
-	return routes, nil
+	matrix, err := s.routeFinder.FindMatrix(ctx, profile, sources, destinations)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return matrix, nil
+}
+
+func (s *routeServiceImpl) GetRoute(ctx context.Context, source, destination Location, opts *RouteOptions) (*RouteDetails, error) {
+	if opts == nil {
+		opts = &RouteOptions{}
+	}
+	profile := opts.profile()
+	geometry := GeometryPolyline
+	if opts.Geometry != "" {
+		geometry = opts.Geometry
+	}
+
+	ctx, span := tracer.Start(ctx, "RouteService.GetRoute")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("route.profile", string(profile)),
+		attribute.String("route.geometry", string(geometry)),
+		attribute.Bool("route.steps", opts.Steps),
+	)
+
+	route, err := s.routeFinder.FindRoute(ctx, profile, source, destination, geometry, opts.Steps)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return route, nil
+}
+
+func (s *routeServiceImpl) GetTrip(ctx context.Context, source Location, stops []Location, opts *TripOptions) (*Trip, error) {
+	if opts == nil {
+		opts = &TripOptions{}
+	}
+	profile := opts.profile()
+	roundtrip := !opts.OneWay && opts.End == ""
+
+	ctx, span := tracer.Start(ctx, "RouteService.GetTrip")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("route.stops", len(stops)),
+		attribute.String("route.profile", string(profile)),
+		attribute.Bool("route.roundtrip", roundtrip),
+	)
+
+	trip, err := s.routeFinder.FindTrip(ctx, profile, source, stops, opts.End, roundtrip)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return trip, nil
+}
+
+// dedupe normalizes the destination locations and returns each distinct one once, in order of first appearance,
+// with the position in unique of every destination. Locations that don't parse are kept as they are.
+func dedupe(destinations []Destination) (unique []Location, positions []int) {
+	positions = make([]int, len(destinations))
+	seen := make(map[Location]int, len(destinations))
+	for i, d := range destinations {
+		location, err := d.Location.Normalize()
+		if err != nil {
+			location = d.Location
+		}
+		position, ok := seen[location]
+		if !ok {
+			position = len(unique)
+			seen[location] = position
+			unique = append(unique, location)
+		}
+		positions[i] = position
+	}
+	return unique, positions
+}
+
+// applySnapPolicy flags or drops the routes whose destination snapped further than allowed
+func applySnapPolicy(routes []*Route, opts *RouteOptions, filtered *FilterCounts) []*Route {
+	if opts.MaxSnapDistance <= 0 {
+		return routes
+	}
+
+	kept := make([]*Route, 0, len(routes))
+	for _, r := range routes {
+		if r.SnapDistance > opts.MaxSnapDistance {
+			if opts.SnapPolicy == SnapPolicyExclude {
+				filtered.SnapTooFar++
+				continue
+			}
+			r.SnapTooFar = true
+		}
+		kept = append(kept, r)
+	}
+
+	return kept
+}
+
+// applyFilters leaves out the sorted routes beyond the max duration, distance and limit of opts
+func applyFilters(routes []*Route, opts *RouteOptions, filtered *FilterCounts) []*Route {
+	if opts.MaxDuration <= 0 && opts.MaxDistance <= 0 && opts.Limit <= 0 {
+		return routes
+	}
+
+	kept := make([]*Route, 0, len(routes))
+	for _, r := range routes {
+		switch {
+		case r.Unreachable && (opts.MaxDuration > 0 || opts.MaxDistance > 0):
+			filtered.Unreachable++
+		case opts.MaxDuration > 0 && r.Duration > opts.MaxDuration:
+			filtered.MaxDuration++
+		case opts.MaxDistance > 0 && r.Distance > opts.MaxDistance:
+			filtered.MaxDistance++
+		case opts.Limit > 0 && len(kept) >= opts.Limit:
+			filtered.Limit++
+		default:
+			kept = append(kept, r)
+		}
+	}
+
+	return kept
+}
+
+// errIncompleteRoutes is returned when a routeFinder doesn't return one route per destination
+var errIncompleteRoutes = errors.New("route finder returned incomplete routes")
+
+// checkRoutes checks that there is one non-nil route per destination, before routes are indexed by destination
+func checkRoutes(routes []*Route, destinations int) error {
+	if len(routes) != destinations {
+		return fmt.Errorf("%w: got %d routes for %d destinations", errIncompleteRoutes, len(routes), destinations)
+	}
+	for i, r := range routes {
+		if r == nil {
+			return fmt.Errorf("%w: route %d is missing", errIncompleteRoutes, i)
+		}
+	}
+	return nil
 }
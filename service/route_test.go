@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticRouteFinder struct {
+	routes []*Route
+	matrix *Matrix
+	route  *RouteDetails
+	trip   *Trip
+}
+
+func (f *staticRouteFinder) FindFastestRoutes(ctx context.Context, profile Profile, source Location, destinations []Location) ([]*Route, error) {
+	return f.routes, nil
+}
+
+func (f *staticRouteFinder) FindMatrix(ctx context.Context, profile Profile, sources, destinations []Location) (*Matrix, error) {
+	return f.matrix, nil
+}
+
+func (f *staticRouteFinder) FindRoute(ctx context.Context, profile Profile, source, destination Location, geometry GeometryFormat, steps bool) (*RouteDetails, error) {
+	return f.route, nil
+}
+
+func (f *staticRouteFinder) FindTrip(ctx context.Context, profile Profile, source Location, stops []Location, end Location, roundtrip bool) (*Trip, error) {
+	return f.trip, nil
+}
+
+// destinationsAt returns destinations without IDs at the locations
+func destinationsAt(locations ...Location) []Destination {
+	destinations := make([]Destination, len(locations))
+	for i, l := range locations {
+		destinations[i] = Destination{Location: l}
+	}
+	return destinations
+}
+
+func TestGetFastestRoutes_Sorting(t *testing.T) {
+	finder := &staticRouteFinder{routes: []*Route{
+		{Destination: "1,1", Unreachable: true},
+		{Destination: "2,2", Duration: 200, Distance: 50},
+		{Destination: "3,3", Duration: 100, Distance: 90},
+		{Destination: "4,4", Duration: 100, Distance: 80},
+		{Destination: "5,5", Unreachable: true},
+	}}
+
+	result, err := NewRouteService(finder).GetFastestRoutes(context.Background(), "0,0", destinationsAt("1,1", "2,2", "3,3", "4,4", "5,5"), nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Location{"4,4", "3,3", "2,2", "1,1", "5,5"}, routeDestinations(result.Routes))
+	assert.Zero(t, result.Filtered.Total())
+}
+
+func TestGetFastestRoutes_Duplicates(t *testing.T) {
+	finder := &staticRouteFinder{routes: []*Route{
+		{Destination: "1,1", Duration: 300},
+		{Destination: "2,2", Duration: 100},
+	}}
+	destinations := []Destination{{Location: "1,1", ID: "a"}, {Location: " 1.0000001, 1.0 ", ID: "b"}, {Location: "2,2"}}
+
+	result, err := NewRouteService(finder).GetFastestRoutes(context.Background(), "0,0", destinations, nil)
+
+	require.NoError(t, err)
+	require.Len(t, result.Routes, 3)
+	assert.Equal(t, []Location{"2,2", "1,1", " 1.0000001, 1.0 "}, routeDestinations(result.Routes))
+	assert.Equal(t, []string{"", "a", "b"}, []string{result.Routes[0].ID, result.Routes[1].ID, result.Routes[2].ID})
+	assert.Equal(t, []int{2, 0, 1}, []int{result.Routes[0].Index, result.Routes[1].Index, result.Routes[2].Index})
+	assert.Equal(t, result.Routes[1].Duration, result.Routes[2].Duration)
+	assert.NotSame(t, result.Routes[1], result.Routes[2])
+}
+
+func TestDedupe(t *testing.T) {
+	unique, positions := dedupe([]Destination{
+		{Location: "52.5200001,13.4"},
+		{Location: " 52.52 , 13.400000 "},
+		{Location: "48.1,11.5"},
+		{Location: "invalid"},
+		{Location: "52.52,13.4"},
+	})
+
+	assert.Equal(t, []Location{"52.52,13.4", "48.1,11.5", "invalid"}, unique)
+	assert.Equal(t, []int{0, 0, 1, 2, 0}, positions)
+}
+
+func TestGetFastestRoutes_RouteCountMismatch(t *testing.T) {
+	finder := &staticRouteFinder{routes: []*Route{{Destination: "1,1"}}}
+
+	_, err := NewRouteService(finder).GetFastestRoutes(context.Background(), "0,0", destinationsAt("1,1", "2,2"), nil)
+
+	assert.Error(t, err)
+}
+
+func routeDestinations(routes []*Route) []Location {
+	destinations := make([]Location, len(routes))
+	for i, r := range routes {
+		destinations[i] = r.Destination
+	}
+	return destinations
+}
+
+func TestGetFastestRoutes_Filters(t *testing.T) {
+	newFinder := func() *staticRouteFinder {
+		return &staticRouteFinder{routes: []*Route{
+			{Destination: "1,1", Duration: 300, Distance: 900},
+			{Destination: "2,2", Duration: 100, Distance: 3000},
+			{Destination: "3,3", Unreachable: true},
+			{Destination: "4,4", Duration: 200, Distance: 800, SnapDistance: 600},
+			{Destination: "5,5", Duration: 150, Distance: 500},
+			{Destination: "6,6", Duration: 50, Distance: 400},
+		}}
+	}
+	destinations := []Location{"1,1", "2,2", "3,3", "4,4", "5,5", "6,6"}
+
+	tests := []struct {
+		name     string
+		opts     *RouteOptions
+		expected []Location
+		filtered FilterCounts
+	}{
+		{
+			name:     "limit",
+			opts:     &RouteOptions{Limit: 2},
+			expected: []Location{"6,6", "2,2"},
+			filtered: FilterCounts{Limit: 4},
+		},
+		{
+			name:     "max duration",
+			opts:     &RouteOptions{MaxDuration: 200},
+			expected: []Location{"6,6", "2,2", "5,5", "4,4"},
+			filtered: FilterCounts{MaxDuration: 1, Unreachable: 1},
+		},
+		{
+			name:     "all filters",
+			opts:     &RouteOptions{MaxSnapDistance: 500, SnapPolicy: SnapPolicyExclude, MaxDuration: 250, MaxDistance: 1000, Limit: 1},
+			expected: []Location{"6,6"},
+			filtered: FilterCounts{SnapTooFar: 1, Unreachable: 1, MaxDuration: 1, MaxDistance: 1, Limit: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewRouteService(newFinder()).GetFastestRoutes(context.Background(), "0,0", destinationsAt(destinations...), tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, routeDestinations(result.Routes))
+			assert.Equal(t, tt.filtered, result.Filtered)
+		})
+	}
+}
+
+func TestGetMatrix_PassesThroughDecorators(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	var decorated routeFinder = NewCoalescingRouteFinder(NewCachingRouteFinder(NewBatchingRouteFinder(finder, nil), nil))
+
+	matrix, err := NewRouteService(decorated).GetMatrix(context.Background(), []Location{"1,1", "2,2"}, []Location{"3,3", "4,4", "5,5"}, nil)
+
+	require.NoError(t, err)
+	require.Len(t, finder.calls, 1)
+	require.Len(t, matrix.Cells, 2)
+	assert.Equal(t, []MatrixCell{{Duration: 103, Distance: 10}, {Duration: 104, Distance: 10}, {Duration: 105, Distance: 10}}, matrix.Cells[1])
+}
+
+func TestGetPlan_RoutesEachLocationOnce(t *testing.T) {
+	finder := &fakeRouteFinder{}
+	vehicles := []PlanVehicle{{Start: "1,1", End: "1,1"}, {Start: "1,1", End: "2,2"}}
+	stops := []PlanStop{{Location: "3,3"}, {Location: "2,2"}, {Location: "3,3"}}
+
+	plan, err := NewRouteService(finder).GetPlan(context.Background(), vehicles, stops, nil)
+
+	require.NoError(t, err)
+	require.Len(t, finder.calls, 1)
+	assert.Equal(t, []Location{"1,1", "2,2", "3,3"}, finder.calls[0])
+	assert.Empty(t, plan.Unassigned)
+	visits := 0
+	for _, route := range plan.Routes {
+		visits += len(route.Visits)
+	}
+	assert.Equal(t, len(stops), visits)
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/osrmclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/server"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+	"github.com/stretchr/testify/suite"
+)
+
+// fallbackSuite runs a server whose route service estimates routes while OSRM is unavailable,
+// as with ROUTE_FALLBACK_ENABLED
+type fallbackSuite struct {
+	suite.Suite
+	server   *server.Server
+	osrmMock *osrmclient.MockOSRMClient
+}
+
+func (suite *fallbackSuite) SetupSuite() {
+	osrmClient := &osrmclient.MockOSRMClient{}
+	suite.server = startServer(&suite.Suite, ":8091", server.Config{
+		RouteService: service.NewRouteService(service.NewFallbackRouteFinder(osrmClient, nil)),
+		OSRM:         osrmClient,
+	})
+	suite.osrmMock = osrmClient
+}
+
+func (suite *fallbackSuite) SetupTest() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return nil, fmt.Errorf("%w: dial tcp: connection refused", service.ErrUnavailable)
+	}
+	suite.osrmMock.PingFunc = func(ctx context.Context) error {
+		return fmt.Errorf("%w: dial tcp: connection refused", service.ErrUnavailable)
+	}
+}
+
+func (suite *fallbackSuite) TearDownSuite() {
+	suite.NoError(suite.server.Stop())
+}
+
+func (suite *fallbackSuite) TestGetFastestRoutes_Estimated() {
+	resp, err := http.Get("http://localhost:8091/routes?src=52.5,13.4&dst=52.6,13.4&dst=52.51,13.4")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("approximate", resp.Header.Get("X-Route-Estimate"))
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 2)
+	suite.Equal(server.Location("52.51,13.4"), actual.Routes[0].Destination)
+	suite.Equal(server.Location("52.6,13.4"), actual.Routes[1].Destination)
+	for _, r := range actual.Routes {
+		suite.True(r.Estimated)
+		suite.Positive(r.Duration)
+	}
+	// 0.01° of latitude is about 1.1km, times the default detour factor
+	suite.InDelta(1445, actual.Routes[0].Distance, 5)
+}
+
+func (suite *fallbackSuite) TestHealth_Unavailable() {
+	// routes are still estimated, but the health check must report the outage
+	resp, err := http.Get("http://localhost:8091/health")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	var body map[string]string
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.Equal("unavailable", body["osrm"])
+}
+
+func TestFallbackIntegration(t *testing.T) {
+	suite.Run(t, new(fallbackSuite))
+}
@@ -1,12 +1,15 @@
 package test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/httpclient"
 	"github.com/mrasoolmirzaei/delivery-route-system/server"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
 	"github.com/stretchr/testify/suite"
@@ -17,7 +20,7 @@ func (suite *testSuite) TestGetFastestRoutes() {
 		name                string
 		request             *server.GetRoutesRequest
 		expected            *server.GetRoutesResponse
-		routeFinderResponse func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error)
+		routeFinderResponse func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error)
 	}{
 		{
 			name: "one destination",
@@ -26,16 +29,18 @@ func (suite *testSuite) TestGetFastestRoutes() {
 				Destinations: []server.Location{"13.1234,12.7890"},
 			},
 			expected: &server.GetRoutesResponse{
-				Source: "12.3456,78.9101",
+				Source:  "12.3456,78.9101",
+				Ranking: "duration",
 				Routes: []*server.Route{
 					{
 						Destination: "13.1234,12.7890",
 						Distance:    100,
 						Duration:    100,
+						Score:       100,
 					},
 				},
 			},
-			routeFinderResponse: func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+			routeFinderResponse: func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
 				return []*service.Route{
 					{
 						Destination: "13.1234,12.7890",
@@ -52,21 +57,25 @@ func (suite *testSuite) TestGetFastestRoutes() {
 				Destinations: []server.Location{"13.1234,12.7890", "14.1516,17.1819"},
 			},
 			expected: &server.GetRoutesResponse{
-				Source: "12.3456,78.9101",
+				Source:  "12.3456,78.9101",
+				Ranking: "duration",
 				Routes: []*server.Route{
 					{
 						Destination: "13.1234,12.7890",
 						Distance:    100,
 						Duration:    100,
+						Score:       100,
 					},
 					{
 						Destination: "14.1516,17.1819",
+						Index:       1,
 						Distance:    120,
 						Duration:    120,
+						Score:       120,
 					},
 				},
 			},
-			routeFinderResponse: func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+			routeFinderResponse: func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
 				return []*service.Route{
 					{
 						Destination: "13.1234,12.7890",
@@ -101,13 +110,134 @@ func (suite *testSuite) TestGetFastestRoutes() {
 	}
 }
 
+func (suite *testSuite) TestGetFastestRoutes_MaxSnapDistance() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return []*service.Route{
+			{Destination: destinations[0], Distance: 100, Duration: 100, SnappedLocation: "13.1235,12.7891", SnapDistance: 12},
+			{Destination: destinations[1], Distance: 120, Duration: 120, SnappedLocation: "14.1600,17.1900", SnapDistance: 850},
+		}, nil
+	}
+
+	cases := []struct {
+		name     string
+		policy   string
+		expected []*server.Route
+	}{
+		{
+			name:   "flag",
+			policy: "flag",
+			expected: []*server.Route{
+				{Destination: "13.1234,12.7890", Distance: 100, Duration: 100, SnappedLocation: "13.1235,12.7891", SnapDistance: 12, Score: 100},
+				{Destination: "14.1516,17.1819", Index: 1, Distance: 120, Duration: 120, SnappedLocation: "14.16,17.19", SnapDistance: 850, SnapTooFar: true, Score: 120},
+			},
+		},
+		{
+			name:   "exclude",
+			policy: "exclude",
+			expected: []*server.Route{
+				{Destination: "13.1234,12.7890", Distance: 100, Duration: 100, SnappedLocation: "13.1235,12.7891", SnapDistance: 12, Score: 100},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		suite.Run(tc.name, func() {
+			resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890&dst=14.1516,17.1819&max_snap_distance=500&snap_policy=" + tc.policy)
+			suite.NoError(err)
+			suite.Equal(http.StatusOK, resp.StatusCode)
+			var actual server.GetRoutesResponse
+			suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+			suite.Equal(tc.expected, actual.Routes)
+		})
+	}
+}
+
+func (suite *testSuite) TestGetFastestRoutes_Unreachable() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return []*service.Route{
+			{Destination: destinations[0], Unreachable: true},
+			{Destination: destinations[1], Distance: 120, Duration: 120},
+		}, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890&dst=14.1516,17.1819")
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var actual struct {
+		Routes []map[string]any `json:"routes"`
+	}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 2)
+	suite.Equal("14.1516,17.1819", actual.Routes[0]["destination"])
+	suite.Equal(120.0, actual.Routes[0]["duration"])
+	suite.Equal("13.1234,12.7890", actual.Routes[1]["destination"])
+	suite.Equal(true, actual.Routes[1]["unreachable"])
+	suite.Contains(actual.Routes[1], "duration")
+	suite.Nil(actual.Routes[1]["duration"])
+	suite.Nil(actual.Routes[1]["distance"])
+	suite.Contains(actual.Routes[1], "score")
+	suite.Nil(actual.Routes[1]["score"])
+}
+
+func (suite *testSuite) TestGetFastestRoutes_CoordOrder() {
+	var gotSource service.Location
+	var gotDestinations []service.Location
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		gotSource, gotDestinations = source, destinations
+		return []*service.Route{
+			{Destination: destinations[0], Distance: 100, Duration: 100, SnappedLocation: "52.5171,13.3889", SnapDistance: 3},
+		}, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=13.388860,52.517037&dst=13.397634,52.529407&coord_order=lonlat")
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+
+	suite.Equal(service.Location("52.517037,13.38886"), gotSource)
+	suite.Equal([]service.Location{"52.529407,13.397634"}, gotDestinations)
+	suite.Equal(server.Location("13.388860,52.517037"), actual.Source)
+	suite.Require().Len(actual.Routes, 1)
+	suite.Equal(server.Location("13.397634,52.529407"), actual.Routes[0].Destination)
+	suite.Equal(server.Location("13.3889,52.5171"), actual.Routes[0].SnappedLocation)
+}
+
+func (suite *testSuite) TestGetFastestRoutes_Profile() {
+	var gotProfile service.Profile
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		gotProfile = profile
+		if profile == service.ProfileWalking {
+			return nil, fmt.Errorf("%w: %s", service.ErrUnsupportedProfile, profile)
+		}
+		return []*service.Route{{Destination: destinations[0], Distance: 100, Duration: 300}}, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890")
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(service.ProfileDriving, gotProfile)
+
+	resp, err = http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890&profile=cycling")
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(service.ProfileCycling, gotProfile)
+
+	resp, err = http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890&profile=walking")
+	suite.NoError(err)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	var body map[string]string
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.Contains(body, "profile")
+}
+
 func (suite *testSuite) TestGetFastestRoutes_Failures() {
 	cases := []struct {
 		name           string
 		url            string
 		expectedStatus int
 		expectedError  string
-		mockFunc       func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error)
+		mockFunc       func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error)
 	}{
 		{
 			name:           "missing source parameter",
@@ -135,7 +265,7 @@ func (suite *testSuite) TestGetFastestRoutes_Failures() {
 			url:            "http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890",
 			expectedStatus: http.StatusServiceUnavailable,
 			expectedError:  "failed to get routes",
-			mockFunc: func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+			mockFunc: func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
 				return nil, fmt.Errorf("failed to get routes")
 			},
 		},
@@ -144,7 +274,7 @@ func (suite *testSuite) TestGetFastestRoutes_Failures() {
 			url:            "http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890",
 			expectedStatus: http.StatusServiceUnavailable,
 			expectedError:  "",
-			mockFunc: func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+			mockFunc: func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
 				return nil, fmt.Errorf("OSRM service timeout")
 			},
 		},
@@ -153,7 +283,7 @@ func (suite *testSuite) TestGetFastestRoutes_Failures() {
 			url:            "http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890",
 			expectedStatus: http.StatusServiceUnavailable,
 			expectedError:  "",
-			mockFunc: func(ctx context.Context, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+			mockFunc: func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
 				return nil, fmt.Errorf("connection refused")
 			},
 		},
@@ -189,6 +319,141 @@ func (suite *testSuite) TestGetFastestRoutes_Failures() {
 	}
 }
 
+func (suite *testSuite) TestPostFastestRoutes() {
+	destinations := make([]server.RouteDestination, 0, 200)
+	for i := 0; i < 200; i++ {
+		destinations = append(destinations, server.RouteDestination{Location: server.Location(fmt.Sprintf("12.%d,78.%d", i, i+100))})
+	}
+
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		routes := make([]*service.Route, len(destinations))
+		for i, d := range destinations {
+			routes[i] = &service.Route{
+				Destination: d,
+				Distance:    float64(1000 - i),
+				Duration:    float64(1000 - i),
+			}
+		}
+		return routes, nil
+	}
+
+	body, err := json.Marshal(server.PostRoutesRequest{
+		Source:       "12.3456,78.9101",
+		Destinations: destinations,
+	})
+	suite.NoError(err)
+
+	resp, err := http.Post("http://localhost:8090/routes", "application/json", bytes.NewReader(body))
+	suite.NoError(err)
+	defer resp.Body.Close()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Equal(server.Location("12.3456,78.9101"), actual.Source)
+	suite.Len(actual.Routes, len(destinations))
+	suite.Equal(destinations[len(destinations)-1].Location, actual.Routes[0].Destination)
+	suite.Equal(len(destinations)-1, actual.Routes[0].Index)
+}
+
+func (suite *testSuite) TestPostFastestRoutes_Failures() {
+	cases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "malformed body",
+			body:           `{"source":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid JSON body",
+		},
+		{
+			name:           "missing destinations",
+			body:           `{"source":"12.3456,78.9101"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "destinations",
+		},
+		{
+			name:           "invalid destination",
+			body:           `{"source":"12.3456,78.9101","destinations":["invalid"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "destination number 1 is invalid",
+		},
+	}
+
+	for _, tc := range cases {
+		suite.Run(tc.name, func() {
+			resp, err := http.Post("http://localhost:8090/routes", "application/json", bytes.NewBufferString(tc.body))
+			suite.NoError(err)
+			defer resp.Body.Close()
+			suite.Equal(tc.expectedStatus, resp.StatusCode)
+
+			var validationErr map[string]interface{}
+			suite.NoError(json.NewDecoder(resp.Body).Decode(&validationErr))
+			suite.Contains(fmt.Sprintf("%v", validationErr), tc.expectedError)
+		})
+	}
+}
+
+func (suite *testSuite) TestGetFastestRoutes_CircuitOpen() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return nil, fmt.Errorf("failed to get table response from OSRM: %w", &httpclient.CircuitOpenError{
+			Host:      "router.project-osrm.org",
+			OpenUntil: time.Now().Add(5 * time.Second),
+		})
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890")
+	suite.NoError(err)
+	defer resp.Body.Close()
+
+	suite.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	suite.Equal("5", resp.Header.Get("Retry-After"))
+	var body map[string]string
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.Equal("routing service unavailable, retry later", body["error"])
+}
+
+func (suite *testSuite) TestFastestRoutes_DestinationIDs() {
+	var asked []service.Location
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		asked = destinations
+		routes := make([]*service.Route, len(destinations))
+		for i, d := range destinations {
+			routes[i] = &service.Route{Destination: d, Distance: float64(300 - 100*i), Duration: float64(300 - 100*i)}
+		}
+		return routes, nil
+	}
+
+	// The same coordinates sent twice, differently formatted, are routed once and keep their own ID and metadata
+	resp := suite.doJSON(http.MethodPost, "http://localhost:8090/routes",
+		`{"source":"12.3456,78.9101","destinations":[{"location":"13.1,12.1","id":"a","metadata":{"slot":1}},{"location":"13.10,12.10","id":"b"},"13.3,12.3"]}`)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal([]service.Location{"13.1,12.1", "13.3,12.3"}, asked)
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 3)
+	suite.Equal([]server.Location{"13.3,12.3", "13.1,12.1", "13.10,12.10"},
+		[]server.Location{actual.Routes[0].Destination, actual.Routes[1].Destination, actual.Routes[2].Destination})
+	suite.Equal([]string{"", "a", "b"}, []string{actual.Routes[0].ID, actual.Routes[1].ID, actual.Routes[2].ID})
+	suite.Equal([]int{2, 0, 1}, []int{actual.Routes[0].Index, actual.Routes[1].Index, actual.Routes[2].Index})
+	suite.Equal(actual.Routes[1].Duration, actual.Routes[2].Duration)
+	suite.JSONEq(`{"slot":1}`, string(actual.Routes[1].Metadata))
+	suite.Empty(actual.Routes[2].Metadata)
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1,12.1&dst=13.2,12.2&dst_ref=a&dst_ref=b")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	actual = server.GetRoutesResponse{}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 2)
+	suite.Equal("b", actual.Routes[0].ID)
+	suite.Equal(1, actual.Routes[0].Index)
+	suite.Equal("a", actual.Routes[1].ID)
+}
+
 func buildURLWithManyDestinations(source string, count int) string {
 	url := fmt.Sprintf("http://localhost:8090/routes?src=%s", source)
 	for i := 0; i < count; i++ {
@@ -200,3 +465,47 @@ func buildURLWithManyDestinations(source string, count int) string {
 func TestIntegration(t *testing.T) {
 	suite.Run(t, new(testSuite))
 }
+
+func (suite *testSuite) TestGetFastestRoutes_Filters() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return []*service.Route{
+			{Destination: destinations[0], Distance: 900, Duration: 300},
+			{Destination: destinations[1], Distance: 3000, Duration: 100},
+			{Destination: destinations[2], Distance: 500, Duration: 150},
+			{Destination: destinations[3], Distance: 400, Duration: 50},
+		}, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1,12.1&dst=13.2,12.2&dst=13.3,12.3&dst=13.4,12.4&limit=1&max_duration=200&max_distance=1000")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 1)
+	suite.Equal(server.Location("13.4,12.4"), actual.Routes[0].Destination)
+	suite.Equal(&server.FilteredRoutes{Total: 3, MaxDuration: 1, MaxDistance: 1, Limit: 1}, actual.Filtered)
+}
+
+func (suite *testSuite) TestGetFastestRoutes_Ranking() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return []*service.Route{
+			{Destination: destinations[0], Distance: 3000, Duration: 100},
+			{Destination: destinations[1], Distance: 1000, Duration: 130},
+		}, nil
+	}
+
+	resp := suite.doJSON(http.MethodPost, "http://localhost:8090/routes",
+		`{"source":"12.3456,78.9101","destinations":["13.1,12.1","13.2,12.2"],"ranking":"weighted","duration_weight":1,"distance_weight":0.1}`)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Equal("weighted", actual.Ranking)
+	suite.Require().Len(actual.Routes, 2)
+	suite.Equal(server.Location("13.2,12.2"), actual.Routes[0].Destination)
+	suite.InDelta(230, actual.Routes[0].Score, 1e-9)
+	suite.InDelta(400, actual.Routes[1].Score, 1e-9)
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1,12.1&ranking=tolerance")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+func (suite *testSuite) TestHealth() {
+	cases := []struct {
+		name           string
+		pingErr        error
+		expectedStatus int
+		expectedOSRM   string
+	}{
+		{name: "healthy", expectedStatus: http.StatusOK, expectedOSRM: "healthy"},
+		{name: "unavailable", pingErr: errors.New("connection refused"), expectedStatus: http.StatusServiceUnavailable, expectedOSRM: "unavailable"},
+		{name: "timeout", pingErr: context.DeadlineExceeded, expectedStatus: http.StatusServiceUnavailable, expectedOSRM: "timeout"},
+	}
+
+	for _, tc := range cases {
+		suite.Run(tc.name, func() {
+			// FindFastestRoutesFunc stays nil: the health check must not go through the route service
+			suite.osrmMock.PingFunc = func(ctx context.Context) error {
+				return tc.pingErr
+			}
+
+			resp, err := http.Get("http://localhost:8090/health")
+			suite.Require().NoError(err)
+			defer resp.Body.Close()
+
+			suite.Equal(tc.expectedStatus, resp.StatusCode)
+			var body map[string]string
+			suite.NoError(json.NewDecoder(resp.Body).Decode(&body))
+			suite.Equal(tc.expectedOSRM, body["osrm"])
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+func (suite *testSuite) TestPostMatrix() {
+	var gotSources, gotDestinations []service.Location
+	suite.osrmMock.FindMatrixFunc = func(ctx context.Context, profile service.Profile, sources, destinations []service.Location) (*service.Matrix, error) {
+		gotSources, gotDestinations = sources, destinations
+		return &service.Matrix{
+			Sources:      sources,
+			Destinations: destinations,
+			Cells: [][]service.MatrixCell{
+				{{Duration: 100, Distance: 1000}, {Unreachable: true}},
+				{{Duration: 200, Distance: 2000}, {Duration: 300, Distance: 3000}},
+			},
+		}, nil
+	}
+
+	body := `{"sources":["13.388860,52.517037","13.4,52.52"],"destinations":["13.397634,52.529407","13.428555,52.523219"],"coord_order":"lonlat"}`
+	resp, err := http.Post("http://localhost:8090/matrix", "application/json", bytes.NewBufferString(body))
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var actual struct {
+		Sources      []string           `json:"sources"`
+		Destinations []string           `json:"destinations"`
+		Cells        [][]map[string]any `json:"cells"`
+	}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+
+	suite.Equal([]service.Location{"52.517037,13.38886", "52.52,13.4"}, gotSources)
+	suite.Equal([]service.Location{"52.529407,13.397634", "52.523219,13.428555"}, gotDestinations)
+	suite.Equal([]string{"13.388860,52.517037", "13.4,52.52"}, actual.Sources)
+	suite.Require().Len(actual.Cells, 2)
+	suite.Equal(map[string]any{"duration": 100.0, "distance": 1000.0}, actual.Cells[0][0])
+	suite.Equal(map[string]any{"duration": nil, "distance": nil, "unreachable": true}, actual.Cells[0][1])
+	suite.Equal(map[string]any{"duration": 300.0, "distance": 3000.0}, actual.Cells[1][1])
+}
+
+func (suite *testSuite) TestPostMatrix_Failures() {
+	cases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "missing sources",
+			body:           `{"destinations":["13.1234,12.7890"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "sources",
+		},
+		{
+			name:           "unknown profile",
+			body:           `{"sources":["12.3456,78.9101"],"destinations":["13.1234,12.7890"],"profile":"flying"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "profile",
+		},
+	}
+
+	for _, tc := range cases {
+		suite.Run(tc.name, func() {
+			resp, err := http.Post("http://localhost:8090/matrix", "application/json", bytes.NewBufferString(tc.body))
+			suite.NoError(err)
+			suite.Equal(tc.expectedStatus, resp.StatusCode)
+			var body map[string]string
+			suite.NoError(json.NewDecoder(resp.Body).Decode(&body))
+			suite.Contains(body, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+func (suite *testSuite) TestMetrics() {
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		return []*service.Route{{Destination: destinations[0], Distance: 100, Duration: 100}}, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=12.3456,78.9101&dst=13.1234,12.7890")
+	suite.NoError(err)
+	resp.Body.Close()
+
+	resp, err = http.Get("http://localhost:8090/metrics")
+	suite.NoError(err)
+	defer resp.Body.Close()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	suite.NoError(err)
+	suite.Contains(string(body), `http_server_requests_total{route="GET /routes",status="200"}`)
+	suite.Contains(string(body), `http_server_request_duration_seconds_bucket{route="GET /routes",status="200"`)
+	suite.Contains(string(body), "route_destinations_per_request_count")
+}
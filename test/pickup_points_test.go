@@ -0,0 +1,184 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/server"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+func (suite *testSuite) doJSON(method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	suite.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	return resp
+}
+
+func (suite *testSuite) TestPickupPoints_CRUD() {
+	resp := suite.doJSON(http.MethodPost, "http://localhost:8090/pickup-points",
+		`{"name":"Kiosk Mitte","location":"52.529407,13.397634","carrier":"DHL","opening_hours":"Mo-Sa 08:00-20:00","capacity":40}`)
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+	var created server.PickupPoint
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&created))
+	suite.NotEmpty(created.ID)
+	suite.Equal("/pickup-points/"+created.ID, resp.Header.Get("Location"))
+
+	resp = suite.doJSON(http.MethodPost, "http://localhost:8090/pickup-points",
+		`{"id":"`+created.ID+`","name":"Duplicate","location":"52.529407,13.397634"}`)
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+
+	resp = suite.doJSON(http.MethodPut, "http://localhost:8090/pickup-points/"+created.ID,
+		`{"name":"Kiosk Mitte","location":"13.397634,52.529407","coord_order":"lonlat","capacity":60}`)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err := http.Get("http://localhost:8090/pickup-points/" + created.ID + "?coord_order=lonlat")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var updated server.PickupPoint
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&updated))
+	suite.Equal(60, updated.Capacity)
+	suite.Empty(updated.Carrier)
+	suite.Equal(server.Location("13.397634,52.529407"), updated.Location)
+	suite.Equal(created.CreatedAt, updated.CreatedAt)
+
+	resp, err = http.Get("http://localhost:8090/pickup-points")
+	suite.Require().NoError(err)
+	var list server.ListPickupPointsResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&list))
+	suite.Len(list.PickupPoints, 1)
+
+	resp = suite.doJSON(http.MethodDelete, "http://localhost:8090/pickup-points/"+created.ID, "")
+	suite.Equal(http.StatusNoContent, resp.StatusCode)
+	resp, err = http.Get("http://localhost:8090/pickup-points/" + created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *testSuite) TestGetFastestRoutes_PickupPointIDs() {
+	resp := suite.doJSON(http.MethodPost, "http://localhost:8090/pickup-points",
+		`{"id":"locker-1","name":"Locker","location":"52.523219,13.428555","carrier":"DHL","capacity":40}`)
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		suite.Equal([]service.Location{"52.529407,13.397634", "52.523219,13.428555"}, destinations)
+		return []*service.Route{
+			{Destination: destinations[0], Duration: 465.2, Distance: 1879.4},
+			{Destination: destinations[1], Duration: 300, Distance: 1000},
+		}, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/routes?src=52.517037,13.388860&dst=52.529407,13.397634&dst_id=locker-1")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var actual server.GetRoutesResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 2)
+	suite.Equal(server.Location("52.523219,13.428555"), actual.Routes[0].Destination)
+	suite.Require().NotNil(actual.Routes[0].PickupPoint)
+	suite.Equal("locker-1", actual.Routes[0].PickupPoint.ID)
+	suite.Equal("DHL", actual.Routes[0].PickupPoint.Carrier)
+	suite.Nil(actual.Routes[1].PickupPoint)
+
+	resp = suite.doJSON(http.MethodPost, "http://localhost:8090/routes",
+		`{"source":"52.517037,13.388860","destination_ids":["missing"]}`)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	var validationErr map[string]string
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&validationErr))
+	suite.Contains(validationErr, "destination_ids[1]")
+}
+
+func (suite *testSuite) TestNearestPickupPoints() {
+	// From west to east along the same street, 1 to 6 kilometers from the source
+	for i, lon := range []string{"13.4032", "13.4179", "13.4326", "13.4474", "13.4621", "13.4768"} {
+		resp := suite.doJSON(http.MethodPost, "http://localhost:8090/pickup-points",
+			`{"id":"locker-`+strconv.Itoa(i+1)+`","name":"Locker","location":"52.517037,`+lon+`"}`)
+		suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	}
+
+	suite.osrmMock.FindFastestRoutesFunc = func(ctx context.Context, profile service.Profile, source service.Location, destinations []service.Location) ([]*service.Route, error) {
+		// n=2 with the default multiplier routes the 6 closest candidates within the radius, i.e. 5
+		suite.Len(destinations, 5)
+		routes := make([]*service.Route, len(destinations))
+		for i, dst := range destinations {
+			routes[i] = &service.Route{Destination: dst, Duration: float64(100 * (i + 1)), Distance: float64(1000 * (i + 1))}
+		}
+		// The third closest is the fastest to drive to
+		routes[2].Duration = 50
+		return routes, nil
+	}
+
+	resp, err := http.Get("http://localhost:8090/pickup-points/nearest?src=52.517037,13.388860&n=2&radius_km=5.5")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var actual server.NearestPickupPointsResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Routes, 2)
+	suite.Equal("locker-3", actual.Routes[0].PickupPoint.ID)
+	suite.Equal(server.Location("52.517037,13.4326"), actual.Routes[0].Destination)
+	suite.Equal("locker-1", actual.Routes[1].PickupPoint.ID)
+
+	resp, err = http.Get("http://localhost:8090/pickup-points/nearest?src=48.137154,11.576124&radius_km=10")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Empty(actual.Routes)
+}
+
+func (suite *testSuite) TestPickupPoints_ImportExport() {
+	file := "external_id,name,latitude,longitude,carrier,capacity\n" +
+		"ext-1,Kiosk Mitte,52.529407,13.397634,DHL,40\n" +
+		"ext-2,Locker,52.523219,13.428555,,\n"
+
+	resp, err := http.Post("http://localhost:8090/pickup-points/import?dry_run=true", "text/csv", strings.NewReader(file))
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var result server.ImportPickupPointsResponse
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&result))
+	suite.Equal(server.ImportPickupPointsResponse{DryRun: true, Created: 2, Errors: []*server.ImportRowError{}}, result)
+
+	resp, err = http.Post("http://localhost:8090/pickup-points/import", "text/csv", strings.NewReader(file))
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	// Importing again with a changed name updates the pickup point instead of adding one
+	resp, err = http.Post("http://localhost:8090/pickup-points/import?format=geojson", "application/octet-stream", strings.NewReader(
+		`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[13.397634,52.529407]},"properties":{"external_id":"ext-1","name":"Kiosk Nord"}}]}`))
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&result))
+	suite.Equal(1, result.Updated)
+
+	resp, err = http.Post("http://localhost:8090/pickup-points/import", "text/csv", strings.NewReader(
+		"external_id,name,latitude,longitude\next-3,,52.5,13.4\n"))
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&result))
+	suite.Equal([]*server.ImportRowError{{Row: 2, ExternalID: "ext-3", Error: "name is required"}}, result.Errors)
+
+	resp, err = http.Get("http://localhost:8090/pickup-points/export?format=geojson")
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("application/geo+json", resp.Header.Get("Content-Type"))
+	var exported struct {
+		Features []struct {
+			Properties struct {
+				ExternalID string `json:"external_id"`
+				Name       string `json:"name"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&exported))
+	suite.Require().Len(exported.Features, 2)
+	names := map[string]string{}
+	for _, f := range exported.Features {
+		names[f.Properties.ExternalID] = f.Properties.Name
+	}
+	suite.Equal(map[string]string{"ext-1": "Kiosk Nord", "ext-2": "Locker"}, names)
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/server"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+// lineMatrix places the locations on a straight road, one kilometer apart per degree of latitude
+func lineMatrix(ctx context.Context, profile service.Profile, sources, destinations []service.Location) (*service.Matrix, error) {
+	matrix := &service.Matrix{Sources: sources, Destinations: destinations, Cells: make([][]service.MatrixCell, len(sources))}
+	for i, src := range sources {
+		from, _ := src.Coordinate()
+		matrix.Cells[i] = make([]service.MatrixCell, len(destinations))
+		for j, dst := range destinations {
+			to, _ := dst.Coordinate()
+			distance := math.Abs(to.Lat-from.Lat) * 1000
+			matrix.Cells[i][j] = service.MatrixCell{Distance: distance, Duration: distance / 10}
+		}
+	}
+	return matrix, nil
+}
+
+func (suite *testSuite) TestPostPlans() {
+	suite.osrmMock.FindMatrixFunc = lineMatrix
+
+	body := `{
+		"vehicles": [
+			{"id": "van-1", "start": "0,13.4", "capacity": 2},
+			{"id": "van-2", "start": "0,13.4", "capacity": 2}
+		],
+		"stops": [
+			{"id": "a", "location": "1,13.4", "demand": 1},
+			{"id": "b", "location": "2,13.4", "demand": 1},
+			{"id": "c", "location": "3,13.4", "demand": 1},
+			{"id": "d", "location": "4,13.4", "demand": 1},
+			{"id": "e", "location": "5,13.4", "demand": 1, "time_window": {"start": 0, "end": 100}}
+		],
+		"seed": 7
+	}`
+	post := func() []byte {
+		resp, err := http.Post("http://localhost:8090/plans", "application/json", bytes.NewBufferString(body))
+		suite.Require().NoError(err)
+		suite.Equal(http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		suite.Require().NoError(err)
+		return data
+	}
+
+	first := post()
+	var actual server.PostPlansResponse
+	suite.Require().NoError(json.Unmarshal(first, &actual))
+
+	// e can't be reached in 100 seconds
+	suite.Equal([]string{"e"}, actual.Unassigned)
+	suite.Require().Len(actual.Routes, 2)
+	visited := make([]string, 0)
+	for _, route := range actual.Routes {
+		suite.LessOrEqual(route.Load, 2.0)
+		for _, stop := range route.Stops {
+			visited = append(visited, stop.ID)
+		}
+	}
+	suite.ElementsMatch([]string{"a", "b", "c", "d"}, visited)
+	suite.Equal(string(first), string(post()), "the same request and seed must produce the same plan")
+}
+
+func (suite *testSuite) TestPostPlans_Failures() {
+	body := `{"vehicles":[{"id":"van-1","start":"0,13.4"}],"stops":[{"id":"a","location":"1,13.4"}],"profile":"flying"}`
+	resp, err := http.Post("http://localhost:8090/plans", "application/json", bytes.NewBufferString(body))
+	suite.NoError(err)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/osrmclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+func (suite *testSuite) TestGetRoute() {
+	var gotGeometry service.GeometryFormat
+	var gotSteps bool
+	suite.osrmMock.FindRouteFunc = func(ctx context.Context, profile service.Profile, source, destination service.Location, geometry service.GeometryFormat, steps bool) (*service.RouteDetails, error) {
+		suite.Equal(service.Location("52.517037,13.38886"), source)
+		suite.Equal(service.Location("52.529407,13.397634"), destination)
+		gotGeometry, gotSteps = geometry, steps
+		route := &service.RouteDetails{
+			Distance: 1884.7,
+			Duration: 260.3,
+			Geometry: service.Geometry{Polyline: "ofp_Ik_vpAilAyu@"},
+		}
+		if geometry == service.GeometryGeoJSON {
+			route.Geometry = service.Geometry{Coordinates: []service.Coordinate{{Lat: 52.517037, Lon: 13.38886}, {Lat: 52.529407, Lon: 13.397634}}}
+		}
+		if steps {
+			route.Steps = []service.RouteStep{
+				{Instruction: "Depart onto Unter den Linden", Name: "Unter den Linden", Distance: 1000, Duration: 100, Maneuver: "depart", Location: service.Coordinate{Lat: 52.517037, Lon: 13.38886}},
+				{Instruction: "Arrive at your destination", Maneuver: "arrive", Location: service.Coordinate{Lat: 52.529407, Lon: 13.397634}},
+			}
+		}
+		return route, nil
+	}
+
+	suite.Run("polyline", func() {
+		resp, err := http.Get("http://localhost:8090/route?src=52.517037,13.388860&dst=52.529407,13.397634")
+		suite.NoError(err)
+		suite.Equal(http.StatusOK, resp.StatusCode)
+
+		var actual map[string]any
+		suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+		suite.Equal(service.GeometryPolyline, gotGeometry)
+		suite.False(gotSteps)
+		suite.Equal("52.517037,13.388860", actual["source"])
+		suite.Equal(1884.7, actual["distance"])
+		suite.Equal("ofp_Ik_vpAilAyu@", actual["geometry"])
+		suite.NotContains(actual, "steps")
+	})
+
+	suite.Run("geojson with steps", func() {
+		resp, err := http.Get("http://localhost:8090/route?src=13.388860,52.517037&dst=13.397634,52.529407&coord_order=lonlat&geometry=geojson&steps=true")
+		suite.NoError(err)
+		suite.Equal(http.StatusOK, resp.StatusCode)
+
+		var actual struct {
+			Geometry struct {
+				Type        string       `json:"type"`
+				Coordinates [][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Steps []map[string]any `json:"steps"`
+		}
+		suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+		suite.True(gotSteps)
+		suite.Equal("LineString", actual.Geometry.Type)
+		suite.Equal([][2]float64{{13.38886, 52.517037}, {13.397634, 52.529407}}, actual.Geometry.Coordinates)
+		suite.Require().Len(actual.Steps, 2)
+		suite.Equal("Depart onto Unter den Linden", actual.Steps[0]["instruction"])
+		suite.Equal("13.38886,52.517037", actual.Steps[0]["location"])
+	})
+}
+
+func (suite *testSuite) TestGetRoute_NoRoute() {
+	suite.osrmMock.FindRouteFunc = func(ctx context.Context, profile service.Profile, source, destination service.Location, geometry service.GeometryFormat, steps bool) (*service.RouteDetails, error) {
+		return nil, fmt.Errorf("%w: %w", service.ErrNoRoute, osrmclient.ErrNoRoute)
+	}
+
+	resp, err := http.Get("http://localhost:8090/route?src=52.517037,13.388860&dst=40.7128,-74.0060")
+	suite.NoError(err)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
@@ -1,45 +1,79 @@
 package test
 
 import (
-	"github.com/mrasoolmirzaei/delivery-route-system/server"
+	"context"
+	"net"
+	"os"
+	"time"
+
 	"github.com/mrasoolmirzaei/delivery-route-system/pkg/osrmclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/registry"
+	"github.com/mrasoolmirzaei/delivery-route-system/server"
 	"github.com/mrasoolmirzaei/delivery-route-system/service"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
-	"os"
 )
 
 type testSuite struct {
 	suite.Suite
-	server *server.Server
+	server   *server.Server
 	osrmMock *osrmclient.MockOSRMClient
+	// pickupPoints is emptied before every test
+	pickupPoints *registry.IndexedStore
 }
 
 func (suite *testSuite) SetupSuite() {
+	osrmClient := &osrmclient.MockOSRMClient{}
+	pickupPoints := registry.NewIndexedStore(registry.NewMemoryStore())
+	suite.server = startServer(&suite.Suite, ":8090", server.Config{
+		RouteService: service.NewRouteService(osrmClient),
+		OSRM:         osrmClient,
+		PickupPoints: pickupPoints,
+	})
+	suite.osrmMock = osrmClient
+	suite.pickupPoints = pickupPoints
+}
+
+// startServer serves cfg with a debug logger on addr and waits until it is listening
+func startServer(suite *suite.Suite, addr string, cfg server.Config) *server.Server {
 	logger := logrus.New()
 	logger.SetLevel(logrus.DebugLevel)
 	logger.SetOutput(os.Stderr)
-	loggerEntry := logrus.NewEntry(logger)
+	cfg.Logger = logrus.NewEntry(logger)
 
-	osrmClient := &osrmclient.MockOSRMClient{}
-	routeService := service.NewRouteService(osrmClient)
-	server, err := server.NewServer(server.Config{
-		Logger:       loggerEntry,
-		RouteService: routeService,
-	})
+	server, err := server.NewServer(cfg)
 	if err != nil {
 		suite.FailNow(err.Error())
 	}
-	suite.server = server
-	suite.osrmMock = osrmClient
 
 	go func() {
-		suite.NoError(server.Serve(":8090"))
+		suite.NoError(server.Serve(addr))
 	}()
+
+	suite.Require().Eventually(func() bool {
+		conn, err := net.Dial("tcp", "localhost"+addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond, "server did not start listening")
+	return server
 }
 
 func (suite *testSuite) SetupTest() {
 	suite.osrmMock.FindFastestRoutesFunc = nil
+	suite.osrmMock.FindMatrixFunc = nil
+	suite.osrmMock.FindRouteFunc = nil
+	suite.osrmMock.FindTripFunc = nil
+	suite.osrmMock.PingFunc = nil
+
+	ctx := context.Background()
+	points, err := suite.pickupPoints.List(ctx)
+	suite.Require().NoError(err)
+	for _, p := range points {
+		suite.Require().NoError(suite.pickupPoints.Delete(ctx, p.ID))
+	}
 }
 
 func (suite *testSuite) TearDownSuite() {
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mrasoolmirzaei/delivery-route-system/pkg/osrmclient"
+	"github.com/mrasoolmirzaei/delivery-route-system/service"
+)
+
+func (suite *testSuite) TestPostTrips() {
+	suite.osrmMock.FindTripFunc = func(ctx context.Context, profile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (*service.Trip, error) {
+		suite.Equal(service.Location("52.517037,13.38886"), source)
+		suite.Len(stops, 2)
+		suite.True(roundtrip)
+		suite.Empty(end)
+		return &service.Trip{
+			Order: []int{1, 0},
+			Legs: []service.TripLeg{
+				{From: source, To: stops[1], Distance: 4123, Duration: 712.6},
+				{From: stops[1], To: stops[0], Distance: 2500, Duration: 400},
+				{From: stops[0], To: source, Distance: 1879.4, Duration: 465.2},
+			},
+			Distance: 8502.4,
+			Duration: 1577.8,
+		}, nil
+	}
+
+	body := `{"source":"52.517037,13.388860","stops":["52.529407,13.397634","52.523219,13.428555"]}`
+	resp, err := http.Post("http://localhost:8090/trips", "application/json", bytes.NewBufferString(body))
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var actual struct {
+		Order []int    `json:"order"`
+		Stops []string `json:"stops"`
+		Legs  []struct {
+			From     string  `json:"from"`
+			To       string  `json:"to"`
+			Duration float64 `json:"duration"`
+		} `json:"legs"`
+		Duration float64 `json:"duration"`
+	}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+
+	suite.Equal([]int{1, 0}, actual.Order)
+	suite.Equal([]string{"52.523219,13.428555", "52.529407,13.397634"}, actual.Stops)
+	suite.Require().Len(actual.Legs, 3)
+	suite.Equal("52.517037,13.388860", actual.Legs[0].From)
+	suite.Equal("52.523219,13.428555", actual.Legs[0].To)
+	suite.Equal("52.517037,13.388860", actual.Legs[2].To)
+	suite.Equal(1577.8, actual.Duration)
+}
+
+func (suite *testSuite) TestPostTrips_FixedEnd() {
+	suite.osrmMock.FindTripFunc = func(ctx context.Context, profile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (*service.Trip, error) {
+		suite.Equal(service.Location("52.520008,13.404954"), end)
+		suite.Equal([]service.Location{"52.529407,13.397634"}, stops)
+		return &service.Trip{
+			Order: []int{0},
+			Legs:  []service.TripLeg{{From: source, To: stops[0]}, {From: stops[0], To: end}},
+		}, nil
+	}
+
+	body := `{"source":"13.388860,52.517037","stops":["13.397634,52.529407"],"end":"13.404954,52.520008","coord_order":"lonlat"}`
+	resp, err := http.Post("http://localhost:8090/trips", "application/json", bytes.NewBufferString(body))
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var actual struct {
+		Legs []map[string]any `json:"legs"`
+	}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(&actual))
+	suite.Require().Len(actual.Legs, 2)
+	suite.Equal("13.404954,52.520008", actual.Legs[1]["to"])
+}
+
+func (suite *testSuite) TestPostTrips_NoTrips() {
+	suite.osrmMock.FindTripFunc = func(ctx context.Context, profile service.Profile, source service.Location, stops []service.Location, end service.Location, roundtrip bool) (*service.Trip, error) {
+		return nil, fmt.Errorf("%w: %w", service.ErrNoTrip, osrmclient.ErrNoTrips)
+	}
+
+	body := `{"source":"52.517037,13.388860","stops":["40.7128,-74.0060"]}`
+	resp, err := http.Post("http://localhost:8090/trips", "application/json", bytes.NewBufferString(body))
+	suite.NoError(err)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}